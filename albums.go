@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/oiweiwei/lastfmq/htmlq"
+	"golang.org/x/net/html"
+)
+
+type Album struct {
+	Title     string `json:"title"`
+	Listeners int    `json:"listeners,omitempty"`
+	Image     string `json:"image,omitempty"`
+	Year      int    `json:"year,omitempty"`
+	// Links are the external store and stream urls by the site name (-links).
+	Links map[string]string `json:"links,omitempty"`
+}
+
+func (c *Client) readTopAlbums(ctx context.Context, bandName string) ([]*Album, error) {
+
+	albums, err := readPages(ctx, c, func(ctx context.Context, pageNum int) ([]*Album, error) {
+		return c.readTopAlbumsPage(ctx, bandName, pageNum)
+	}, c.cfg.AlbumsPages, 0)
+	if err != nil {
+		return nil, fmt.Errorf("read_top_albums: %w", err)
+	}
+
+	if c.cfg.AlbumsSort == "year" {
+		sortAlbumsByYear(albums)
+	}
+
+	return albums, nil
+}
+
+// sortAlbumsByYear function sorts the albums by the release year, the albums without the
+// year go last, in the page order.
+func sortAlbumsByYear(albums []*Album) {
+	slices.SortStableFunc(albums, func(a, b *Album) int {
+		switch {
+		case a.Year == b.Year:
+			return 0
+		case a.Year == 0:
+			return 1
+		case b.Year == 0:
+			return -1
+		}
+		return a.Year - b.Year
+	})
+}
+
+// yearRe matches the release year, e.g. in "12 tracks · 20 May 1990".
+var yearRe = regexp.MustCompile(`\b(1[89]\d\d|20\d\d)\b`)
+
+// parseYear function returns the first year in the release info, so the range has the
+// start year, or zero if there is no year, e.g. "TBA".
+func parseYear(s string) int {
+	year, _ := strconv.Atoi(yearRe.FindString(s))
+	return year
+}
+
+func (c *Client) readTopAlbumsPage(ctx context.Context, bandName string, pageNum int) ([]*Album, error) {
+
+	if bandName == "" {
+		return nil, fmt.Errorf("page %d: band name is required", pageNum)
+	}
+
+	resp, err := c.fetch(ctx, albumsPagePath, bandName, pageNum)
+	if err != nil {
+		return nil, fmt.Errorf("page %d: %w", pageNum, err)
+	}
+
+	defer resp.Body.Close()
+
+	// check page number in case of overflow.
+	if pageNum > 1 && resp.Request.URL.Query().Get("page") != strconv.Itoa(pageNum) {
+		return nil, nil
+	}
+
+	tokenizer := html.NewTokenizer(resp.Body)
+
+	var (
+		albums     []*Album
+		startAlbum bool
+	)
+
+	for tok := tokenizer.Next(); tokenizer.Err() == nil; tok = tokenizer.Next() {
+
+		switch tok {
+		case html.EndTagToken:
+			if startAlbum {
+				if htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("ol", "")) != "" {
+					startAlbum = false
+				}
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if !startAlbum {
+				if htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("ol", "class", "resource-list--release-list")) != "" {
+					startAlbum = true
+				}
+				continue
+			}
+
+			switch attr, iter := htmlq.MatchAttr(tokenizer,
+				htmlq.TagAttr("li", "class", "resource-list--release-list-item-wrap"),
+				htmlq.TagAttr("a", "class", "link-block-target"),
+				htmlq.TagAttr("p", "class", "resource-list--release-list-item-listeners", "resource-list--release-list-item-aux-text"),
+				htmlq.TagAttr("img", "src", "*"),
+				htmlq.TagAttr("a", "")); attr {
+
+			case "resource-list--release-list-item-wrap":
+				albums = append(albums, &Album{})
+			case "":
+				// noop.
+			default:
+
+				if len(albums) == 0 {
+					continue
+				}
+
+				album := albums[len(albums)-1]
+
+				switch attr {
+				case "link-block-target":
+					album.Title = htmlq.ReadText(tokenizer, "a")
+				case "resource-list--release-list-item-listeners":
+					if tokenizer.Next() != html.TextToken {
+						continue
+					}
+					album.Listeners = parseCount(string(tokenizer.Text()))
+				case "resource-list--release-list-item-aux-text":
+					album.Year = parseYear(htmlq.ReadText(tokenizer, "p"))
+				case "a":
+					if c.cfg.Links {
+						addLink(&album.Links, iter)
+					}
+				default:
+					// img src=*
+					album.Image = attr
+				}
+			}
+		}
+	}
+
+	if err := tokenizer.Err(); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("page %d: tokenizer: %w", pageNum, err)
+	}
+
+	return albums, nil
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+)
+
+// batchError is the batch record for the band that could not be read.
+type batchError struct {
+	BandName string `json:"band_name"`
+	Error    string `json:"error"`
+}
+
+// readBatch function reads the band names, one per line, skipping the empty lines
+// and the # comments.
+func readBatch(r io.Reader) ([]string, error) {
+
+	var (
+		names   []string
+		scanner = bufio.NewScanner(r)
+	)
+
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" && !strings.HasPrefix(name, "#") {
+			names = append(names, name)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read_batch: %w", err)
+	}
+
+	return names, nil
+}
+
+// openBatch function opens the -batch or -from-ndjson file, "-" stands for stdin.
+func openBatch(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// batchWriter writes the batch records as soon as they are ready. In ordered mode the
+// records are written in the input order, so only the records that are ahead of the
+// first pending band are buffered. In array mode the records are written as the
+// elements of one json array.
+type batchWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	ordered bool
+	next    int
+	pending map[int][]byte
+	array   bool
+	count   int
+}
+
+// open function writes the opening bracket in array mode.
+func (b *batchWriter) open() error {
+	if !b.array {
+		return nil
+	}
+	_, err := io.WriteString(b.w, "[\n")
+	return err
+}
+
+// close function writes the closing bracket in array mode, so the output is valid
+// json even if the batch has stopped early.
+func (b *batchWriter) close() error {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.array {
+		return nil
+	}
+
+	end := "]\n"
+	if b.count > 0 {
+		end = "\n" + end
+	}
+
+	_, err := io.WriteString(b.w, end)
+	return err
+}
+
+// emit function writes the record, in array mode the records are separated with commas
+// and the empty (filtered out) records are skipped.
+func (b *batchWriter) emit(record []byte) error {
+
+	if !b.array {
+		_, err := b.w.Write(record)
+		return err
+	}
+
+	if record = bytes.TrimRight(record, "\n"); len(record) == 0 {
+		return nil
+	}
+
+	if b.count++; b.count > 1 {
+		if _, err := io.WriteString(b.w, ",\n"); err != nil {
+			return err
+		}
+	}
+
+	_, err := b.w.Write(record)
+	return err
+}
+
+func (b *batchWriter) write(i int, record []byte) error {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.ordered {
+		return b.emit(record)
+	}
+
+	b.pending[i] = record
+
+	for record, ok := b.pending[b.next]; ok; record, ok = b.pending[b.next] {
+		if err := b.emit(record); err != nil {
+			return err
+		}
+		delete(b.pending, b.next)
+		b.next++
+	}
+
+	return nil
+}
+
+// runBatch function reads the bands with -batch-workers workers and writes one record
+// per band. The bands that could not be read are reported with the error records, and
+// the run fails after all the bands are processed.
+func (c *Client) runBatch(ctx context.Context, names []string, w io.Writer, with sections) (err error) {
+
+	var (
+		out      = &batchWriter{w: w, ordered: c.cfg.Ordered, pending: make(map[int][]byte), array: c.cfg.BatchArray}
+		tasks    = make([]func(context.Context) error, len(names))
+		failures atomic.Int32
+		files    map[string]string
+	)
+
+	if c.cfg.OutputDir != "" {
+		files = bandFileNames(names)
+	}
+
+	if err := out.open(); err != nil {
+		return fmt.Errorf("run_batch: write: %w", err)
+	}
+
+	defer func() {
+		if cerr := out.close(); cerr != nil && err == nil {
+			err = fmt.Errorf("run_batch: write: %w", cerr)
+		}
+	}()
+
+	for i, name := range names {
+		tasks[i] = func(ctx context.Context) error {
+
+			record, err := c.batchRecord(ctx, name, with)
+			if err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+				stats.failures.Add(1)
+				failures.Add(1)
+				var b bytes.Buffer
+				if err := encodeJSON(&b, &batchError{BandName: name, Error: err.Error()}, c.cfg.Pretty); err != nil {
+					return fmt.Errorf("run_batch: marshal: %w", err)
+				}
+				record = b.Bytes()
+			} else if c.cfg.OutputDir != "" && len(record) > 0 {
+				if err := c.writeBandFile(name, files[name], record); err != nil {
+					return fmt.Errorf("run_batch: %w", err)
+				}
+				// the ordered writer still needs the record to move on.
+				record = nil
+			}
+
+			if err := out.write(i, record); err != nil {
+				return fmt.Errorf("run_batch: write: %w", err)
+			}
+
+			return nil
+		}
+	}
+
+	if err := runTasks(ctx, c.cfg.BatchWorkers, tasks...); err != nil {
+		return err
+	}
+
+	if n := failures.Load(); n > 0 {
+		return fmt.Errorf("run_batch: %d of %d bands failed", n, len(names))
+	}
+
+	return nil
+}
+
+// writeBandFile function writes the band record into the output directory file, the
+// existing file is kept if the overwrite is disabled.
+func (c *Client) writeBandFile(name, fileName string, record []byte) error {
+
+	ext := ".json"
+	if c.cfg.Format == "text" || c.cfg.Format == "template" {
+		ext = ".txt"
+	}
+
+	path := filepath.Join(c.cfg.OutputDir, fileName+ext)
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if c.cfg.NoOverwrite {
+		flags |= os.O_EXCL
+	}
+
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		if c.cfg.NoOverwrite && errors.Is(err, fs.ErrExist) {
+			if c.cfg.Verbose {
+				log.Printf("batch: %s: %s exists, skipped", name, path)
+			}
+			return nil
+		}
+		return fmt.Errorf("write_band_file: %w", err)
+	}
+
+	if _, err = f.Write(record); err != nil {
+		f.Close()
+		return fmt.Errorf("write_band_file: %w", err)
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("write_band_file: %w", err)
+	}
+
+	return nil
+}
+
+// bandFileNames function returns the output file names by the batch band names. The
+// band name colliding with the earlier band's file name, e.g. "AC DC" after "AC/DC",
+// gets the name hash suffix, so the band files don't overwrite each other. The names
+// are compared case-insensitively for the case-insensitive file systems.
+func bandFileNames(names []string) map[string]string {
+
+	var (
+		ret   = make(map[string]string, len(names))
+		taken = make(map[string]bool, len(names))
+	)
+
+	for _, name := range names {
+
+		if _, ok := ret[name]; ok {
+			continue
+		}
+
+		fileName := bandFileName(name)
+		if taken[strings.ToLower(fileName)] {
+			h := fnv.New32a()
+			h.Write([]byte(name))
+			fileName = fmt.Sprintf("%s-%08x", fileName, h.Sum32())
+		}
+
+		ret[name], taken[strings.ToLower(fileName)] = fileName, true
+	}
+
+	return ret
+}
+
+// bandFileName function returns the band name as the safe file name: the letters, the
+// digits, "-" and "." are kept, the runs of the other characters are replaced with "_".
+func bandFileName(name string) string {
+
+	var b strings.Builder
+
+	for _, r := range strings.TrimSpace(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.' {
+			b.WriteRune(r)
+		} else if s := b.String(); s != "" && !strings.HasSuffix(s, "_") {
+			b.WriteRune('_')
+		}
+	}
+
+	// the dot-only names, e.g. "..", are not the safe names.
+	ret := strings.TrimRight(b.String(), "_")
+	if strings.Trim(ret, ".") == "" {
+		ret = "_" + ret
+	}
+
+	return ret
+}
+
+// batchRecord function reads the band and renders its record, the record is empty
+// if the band is filtered out or saved into the sqlite database.
+func (c *Client) batchRecord(ctx context.Context, name string, with sections) ([]byte, error) {
+
+	name, err := c.resolveBand(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := c.readBand(ctx, name, with)
+	if err != nil {
+		return nil, err
+	}
+
+	// the band is read to get the listeners, but not written.
+	if c.cfg.MinListeners > 0 && desc.Listeners < c.cfg.MinListeners {
+		if stats.filtered.Add(1); c.cfg.Verbose {
+			log.Printf("batch: %s: %d listeners is below -min-listeners", name, desc.Listeners)
+		}
+		return nil, nil
+	}
+
+	if c.cfg.SQLite != nil {
+		return nil, c.cfg.SQLite.save(ctx, desc)
+	}
+
+	return c.renderBand(desc, with)
+}
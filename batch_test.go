@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadBatch(t *testing.T) {
+
+	names, err := readBatch(strings.NewReader("Fugazi\n\n# comment\n  Minor Threat  \n"))
+	if err != nil {
+		t.Fatalf("read_batch: %v", err)
+	}
+
+	if expected := []string{"Fugazi", "Minor Threat"}; !slices.Equal(names, expected) {
+		t.Fatalf("read_batch: expected %q, got %q", expected, names)
+	}
+}
+
+func TestRunBatchOrdered(t *testing.T) {
+
+	cfg := DefaultConfig()
+	cfg.Ordered, cfg.BatchWorkers = true, 3
+
+	c := newFixtureClient(t, map[string]string{
+		"/music/Fugazi":       "overview.html",
+		"/music/Unknown+Band": "overview_minimal.html",
+	}, WithConfig(cfg))
+
+	names := []string{"Fugazi", "Nobody", "Unknown+Band", "Fugazi"}
+
+	var b strings.Builder
+
+	if err := c.runBatch(context.Background(), names, &b, sections{}); err == nil {
+		t.Fatalf("run_batch: expected failure for the missing band")
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != len(names) {
+		t.Fatalf("run_batch: expected %d records, got %d: %q", len(names), len(lines), lines)
+	}
+
+	for i, line := range lines {
+
+		var record struct {
+			BandName string `json:"band_name"`
+			Error    string `json:"error"`
+		}
+
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("run_batch: %d: %v", i, err)
+		}
+
+		if expected := strings.ReplaceAll(names[i], "+", " "); record.BandName != expected {
+			t.Errorf("run_batch: %d: expected %q, got %q", i, expected, record.BandName)
+		}
+
+		if failed := names[i] == "Nobody"; failed != (record.Error != "") {
+			t.Errorf("run_batch: %d: unexpected error %q", i, record.Error)
+		}
+	}
+}
+
+func TestRunBatchMinListeners(t *testing.T) {
+
+	cfg := DefaultConfig()
+	cfg.Ordered, cfg.MinListeners = true, 1000
+
+	c := newFixtureClient(t, map[string]string{
+		"/music/Fugazi":       "overview.html",
+		"/music/Unknown+Band": "overview_minimal.html",
+	}, WithConfig(cfg))
+
+	var b strings.Builder
+
+	if err := c.runBatch(context.Background(), []string{"Unknown+Band", "Fugazi"}, &b, sections{}); err != nil {
+		t.Fatalf("run_batch: %v", err)
+	}
+
+	if lines := strings.Split(strings.TrimSpace(b.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"band_name":"Fugazi"`) {
+		t.Fatalf("run_batch: expected only Fugazi record, got %q", lines)
+	}
+}
+
+func TestRunBatchArray(t *testing.T) {
+
+	for _, tc := range []struct {
+		name    string
+		pretty  bool
+		names   []string
+		records []string
+	}{
+		{"empty", false, nil, []string{}},
+		{"filtered and failed", false, []string{"Unknown+Band", "Fugazi", "Nobody"}, []string{"Fugazi", "Nobody"}},
+		{"pretty", true, []string{"Fugazi", "Fugazi"}, []string{"Fugazi", "Fugazi"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			cfg := DefaultConfig()
+			cfg.Ordered, cfg.BatchArray, cfg.Pretty, cfg.MinListeners = true, true, tc.pretty, 1000
+
+			c := newFixtureClient(t, map[string]string{
+				"/music/Fugazi":       "overview.html",
+				"/music/Unknown+Band": "overview_minimal.html",
+			}, WithConfig(cfg))
+
+			var b strings.Builder
+
+			// the failed band fails the run, but the array is closed anyway.
+			_ = c.runBatch(context.Background(), tc.names, &b, sections{})
+
+			var records []struct {
+				BandName string `json:"band_name"`
+			}
+
+			if err := json.Unmarshal([]byte(b.String()), &records); err != nil {
+				t.Fatalf("run_batch: invalid json array %q: %v", b.String(), err)
+			}
+
+			names := []string{}
+			for _, record := range records {
+				names = append(names, record.BandName)
+			}
+
+			if !slices.Equal(names, tc.records) {
+				t.Fatalf("run_batch: expected %q, got %q", tc.records, names)
+			}
+		})
+	}
+}
+
+func TestBandFileName(t *testing.T) {
+
+	for name, expected := range map[string]string{
+		"Fugazi":            "Fugazi",
+		"Minor Threat":      "Minor_Threat",
+		"AC/DC":             "AC_DC",
+		" Sigur Rós ":       "Sigur_Rós",
+		"!!!":               "_",
+		"..":                "_..",
+		"Godspeed You! Bl…": "Godspeed_You_Bl",
+	} {
+		if actual := bandFileName(name); actual != expected {
+			t.Errorf("band_file_name: %q: expected %q, got %q", name, expected, actual)
+		}
+	}
+}
+
+func TestBandFileNames(t *testing.T) {
+
+	files := bandFileNames([]string{"AC/DC", "AC DC", "Fugazi", "ac_dc", "AC/DC"})
+
+	if files["AC/DC"] != "AC_DC" || files["Fugazi"] != "Fugazi" {
+		t.Fatalf("band_file_names: unexpected file names %q", files)
+	}
+
+	seen := make(map[string]bool)
+
+	for _, name := range []string{"AC/DC", "AC DC", "Fugazi", "ac_dc"} {
+		if file := strings.ToLower(files[name]); seen[file] {
+			t.Fatalf("band_file_names: %s: file name %q collides, got %q", name, files[name], files)
+		} else {
+			seen[file] = true
+		}
+	}
+}
+
+func TestRunBatchOutputDir(t *testing.T) {
+
+	dir := t.TempDir()
+
+	cfg := DefaultConfig()
+	cfg.OutputDir, cfg.Ordered, cfg.NoOverwrite = dir, true, true
+
+	c := newFixtureClient(t, map[string]string{
+		"/music/Fugazi":       "overview.html",
+		"/music/Unknown+Band": "overview_minimal.html",
+	}, WithConfig(cfg))
+
+	if err := os.WriteFile(filepath.Join(dir, "Unknown_Band.json"), []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+
+	if err := c.runBatch(context.Background(), []string{"Fugazi", "Nobody", "Unknown+Band"}, &b, sections{}); err == nil {
+		t.Fatalf("run_batch: expected failure for the missing band")
+	}
+
+	// only the error records are written to the output.
+	if lines := strings.Split(strings.TrimSpace(b.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"band_name":"Nobody"`) {
+		t.Fatalf("run_batch: expected only Nobody error record, got %q", lines)
+	}
+
+	record, err := os.ReadFile(filepath.Join(dir, "Fugazi.json"))
+	if err != nil || !strings.Contains(string(record), `"band_name":"Fugazi"`) {
+		t.Fatalf("run_batch: unexpected Fugazi file %q: %v", record, err)
+	}
+
+	if record, _ := os.ReadFile(filepath.Join(dir, "Unknown_Band.json")); string(record) != "{}\n" {
+		t.Fatalf("run_batch: expected the existing file kept, got %q", record)
+	}
+}
+
+func TestRunBatchOutputDirCollision(t *testing.T) {
+
+	dir := t.TempDir()
+
+	cfg := DefaultConfig()
+	cfg.OutputDir = dir
+
+	c := newFixtureClient(t, map[string]string{
+		"/music/AC%2FDC": "overview.html",
+		"/music/AC+DC":   "overview_minimal.html",
+	}, WithConfig(cfg))
+
+	if err := c.runBatch(context.Background(), []string{"AC/DC", "AC+DC"}, io.Discard, sections{}); err != nil {
+		t.Fatalf("run_batch: %v", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 {
+		t.Fatalf("run_batch: expected 2 band files, got %v", files)
+	}
+
+	// both bands are kept, the second one with the name hash suffix.
+	suffixed := bandFileNames([]string{"AC/DC", "AC+DC"})["AC+DC"] + ".json"
+
+	for name, expected := range map[string]string{
+		"AC_DC.json": `"band_name":"Fugazi"`,
+		suffixed:     `"band_name":"Unknown Band"`,
+	} {
+		if record, err := os.ReadFile(filepath.Join(dir, name)); err != nil || !strings.Contains(string(record), expected) {
+			t.Fatalf("run_batch: %s: expected %s, got %q, %v", name, expected, record, err)
+		}
+	}
+}
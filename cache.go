@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheTransport keeps the last.fm responses in the directory, one file per page url,
+// so the repeated runs don't re-fetch the same pages. The 404 responses (the missing
+// bands and sections) are kept for the shorter negative ttl. The redirects are kept as
+// well, so the client follows them from the cache. The expired pages with the ETag or
+// Last-Modified validators are re-checked with the conditional request, and the cached
+// page is reused on the 304 Not Modified response.
+type cacheTransport struct {
+	http.RoundTripper
+	dir              string
+	ttl, negativeTTL time.Duration
+	// refresh re-fetches the pages, but still updates the cache.
+	refresh bool
+	// offline serves the cached pages regardless of the ttl and fails the missing
+	// pages with ErrCacheMiss, without any request.
+	offline bool
+	// maxBodyBytes fails the pages larger than the size with ErrBodyTooLarge before
+	// they are buffered and cached (no limit if zero).
+	maxBodyBytes int64
+}
+
+func newCacheTransport(rt http.RoundTripper, dir string, ttl, negativeTTL time.Duration, refresh, offline bool, maxBodyBytes int64) *cacheTransport {
+	return &cacheTransport{RoundTripper: rt, dir: dir, ttl: ttl, negativeTTL: negativeTTL, refresh: refresh, offline: offline, maxBodyBytes: maxBodyBytes}
+}
+
+// cacheMisses is the list of the page urls missing in the -offline cache.
+var cacheMisses = &missSet{}
+
+// missSet is the set of the missing page urls in the order of the requests.
+type missSet struct {
+	mu   sync.Mutex
+	urls []string
+}
+
+func (s *missSet) add(u string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !slices.Contains(s.urls, u) {
+		s.urls = append(s.urls, u)
+	}
+}
+
+func (s *missSet) list() []string {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.urls)
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	if req.Method != http.MethodGet {
+		return t.RoundTripper.RoundTrip(req)
+	}
+
+	path := filepath.Join(t.dir, cacheFileName(req))
+
+	var cached *http.Response
+
+	if !t.refresh {
+		var fresh bool
+		if cached, fresh = t.read(req, path); fresh || (t.offline && cached != nil) {
+			cacheRequests.WithLabelValues("hit").Inc()
+			return cached, nil
+		}
+	}
+
+	cacheRequests.WithLabelValues("miss").Inc()
+
+	if t.offline {
+		cacheMisses.add(req.URL.String())
+		return nil, fmt.Errorf("%w: %s", ErrCacheMiss, req.URL)
+	}
+
+	resp, err := t.RoundTripper.RoundTrip(conditionalRequest(req, cached))
+	if err != nil {
+		return nil, err
+	}
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		// the page is unchanged, so the cache entry is valid for another ttl.
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); err != nil && verbose {
+			log.Printf("cache: %s: %v", req.URL, err)
+		}
+		return cached, nil
+	}
+
+	if !isCacheable(resp.StatusCode) {
+		return resp, nil
+	}
+
+	var body io.Reader = resp.Body
+	if t.maxBodyBytes > 0 {
+		body = &limitReader{Reader: resp.Body, max: t.maxBodyBytes}
+	}
+
+	b, err := io.ReadAll(body)
+	if resp.Body.Close(); err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+
+	// the consent page is not the page content, so is re-fetched with the cookie.
+	if isConsentPage(b[:min(len(b), consentPeekSize)]) {
+		return resp, nil
+	}
+
+	// the cache is best effort, the failed write doesn't fail the request.
+	if err := t.write(resp, path, b); err != nil && verbose {
+		log.Printf("cache: %s: %v", req.URL, err)
+	}
+
+	return resp, nil
+}
+
+// read function returns the cached response, if any, and whether it is not expired.
+func (t *cacheTransport) read(req *http.Request, path string) (*http.Response, bool) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, false
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(f), req)
+	if err != nil {
+		return nil, false
+	}
+
+	ttl := t.ttl
+	if resp.StatusCode == http.StatusNotFound {
+		ttl = t.negativeTTL
+	}
+
+	fresh := ttl <= 0 || time.Since(info.ModTime()) <= ttl
+
+	// the file is closed on return, so the body is read in advance.
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+
+	return resp, fresh
+}
+
+// conditionalRequest function returns the request with the If-None-Match and the
+// If-Modified-Since headers from the cached response validators, or the request as is
+// if there is no cached response or it has no validators.
+func conditionalRequest(req *http.Request, cached *http.Response) *http.Request {
+
+	if cached == nil {
+		return req
+	}
+
+	etag, lastModified := cached.Header.Get("ETag"), cached.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return req
+	}
+
+	req = req.Clone(req.Context())
+
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	return req
+}
+
+// write function stores the response with the headers into the cache file, the file
+// is replaced at once, so the concurrent reads never see the partial response.
+func (t *cacheTransport) write(resp *http.Response, path string, b []byte) error {
+
+	resp.ContentLength = int64(len(b))
+	// the body is stored uncompressed.
+	resp.Header.Del("Content-Encoding")
+	resp.TransferEncoding = nil
+
+	dump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(t.dir, ".cache-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(dump); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+	}
+
+	return err
+}
+
+// isCacheable function reports whether the response is kept in the cache: the pages,
+// the redirects to the canonical band names and the missing pages.
+func isCacheable(code int) bool {
+	switch code {
+	case http.StatusOK, http.StatusMovedPermanently, http.StatusFound, http.StatusNotFound:
+		return true
+	}
+	return false
+}
+
+// cacheFileName function returns the cache file name for the request url, the path
+// includes the section, i.e. music_Fugazi_+tags_page=1.http.
+func cacheFileName(req *http.Request) string {
+	return strings.TrimSuffix(dumpFileName(req), ".html") + ".http"
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheTransport(t *testing.T) {
+
+	var requests atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/music/fugazi":
+			http.Redirect(w, r, "/music/Fugazi", http.StatusMovedPermanently)
+		case "/music/Fugazi":
+			http.ServeFile(w, r, filepath.Join("testdata", "overview.html"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+
+	newClient := func(refresh bool) *Client {
+		transport := newCacheTransport(srv.Client().Transport, dir, time.Hour, time.Minute, refresh, false, 0)
+		return NewClient(WithBaseURL(srv.URL), WithHTTPClient(&http.Client{Transport: transport}))
+	}
+
+	read := func(c *Client) {
+
+		desc, err := c.readOverview(context.Background(), "fugazi")
+		if err != nil {
+			t.Fatalf("read_overview: %v", err)
+		}
+
+		if desc.CanonicalName != "Fugazi" || desc.BandName != "Fugazi" {
+			t.Fatalf("read_overview: unexpected band %q (%q)", desc.BandName, desc.CanonicalName)
+		}
+
+		if _, err = c.readOverview(context.Background(), "Nobody"); !errors.Is(err, ErrBandNotFound) {
+			t.Fatalf("read_overview: expected band not found, got %v", err)
+		}
+	}
+
+	read(newClient(false))
+
+	if n := requests.Load(); n != 3 {
+		t.Fatalf("cache: expected 3 requests, got %d", n)
+	}
+
+	// the redirect, the page and the missing band are read from the cache.
+	read(newClient(false))
+
+	if n := requests.Load(); n != 3 {
+		t.Fatalf("cache: expected no new requests, got %d", n-3)
+	}
+
+	// the expired missing band is re-checked.
+	past := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "music_Nobody.http"), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	read(newClient(false))
+
+	if n := requests.Load(); n != 4 {
+		t.Fatalf("cache: expected 1 new request for the expired page, got %d", n-3)
+	}
+
+	read(newClient(true))
+
+	if n := requests.Load(); n != 7 {
+		t.Fatalf("cache: expected 3 new requests on refresh, got %d", n-4)
+	}
+}
+
+func TestCacheTransportConditional(t *testing.T) {
+
+	var requests, notModified atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+		}
+		// the file server replies 304 for the matching validators.
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeFile(w, r, filepath.Join("testdata", "overview.html"))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+
+	transport := newCacheTransport(srv.Client().Transport, dir, time.Hour, time.Minute, false, false, 0)
+	c := NewClient(WithBaseURL(srv.URL), WithHTTPClient(&http.Client{Transport: transport}))
+
+	read := func() {
+		desc, err := c.readOverview(context.Background(), "Fugazi")
+		if err != nil {
+			t.Fatalf("read_overview: %v", err)
+		}
+		if desc.BandName != "Fugazi" {
+			t.Fatalf("read_overview: unexpected band %q", desc.BandName)
+		}
+	}
+
+	read()
+
+	path := filepath.Join(dir, "music_Fugazi.http")
+
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	// the expired page is re-checked and reused.
+	read()
+
+	if n, m := requests.Load(), notModified.Load(); n != 2 || m != 1 {
+		t.Fatalf("cache: expected 2 requests with 1 conditional, got %d, %d", n, m)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if time.Since(info.ModTime()) > time.Minute {
+		t.Fatalf("cache: expected the refreshed cache timestamp, got %v", info.ModTime())
+	}
+
+	// the refreshed page is fresh again.
+	read()
+
+	if n := requests.Load(); n != 2 {
+		t.Fatalf("cache: expected no new requests, got %d", n-2)
+	}
+}
+
+func TestCacheTransportOffline(t *testing.T) {
+
+	var requests atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.ServeFile(w, r, filepath.Join("testdata", "overview.html"))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	misses := cacheMisses
+	cacheMisses = &missSet{}
+	t.Cleanup(func() { cacheMisses = misses })
+
+	dir := t.TempDir()
+
+	newClient := func(offline bool) *Client {
+		transport := newCacheTransport(srv.Client().Transport, dir, time.Hour, time.Minute, false, offline, 0)
+		return NewClient(WithBaseURL(srv.URL), WithHTTPClient(&http.Client{Transport: transport}))
+	}
+
+	if _, err := newClient(false).readOverview(context.Background(), "Fugazi"); err != nil {
+		t.Fatalf("read_overview: %v", err)
+	}
+
+	// the expired page is still served offline.
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "music_Fugazi.http"), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newClient(true)
+
+	if _, err := c.readOverview(context.Background(), "Fugazi"); err != nil {
+		t.Fatalf("read_overview: %v", err)
+	}
+
+	if _, err := c.readWiki(context.Background(), "Fugazi"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("read_wiki: expected %v, got %v", ErrCacheMiss, err)
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("cache: expected no requests offline, got %d", n-1)
+	}
+
+	if misses := cacheMisses.list(); len(misses) != 1 || misses[0] != srv.URL+"/music/Fugazi/+wiki" {
+		t.Fatalf("cache: unexpected misses %q", misses)
+	}
+}
+
+func TestCacheTransportMaxBodyBytes(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join("testdata", "overview.html"))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+
+	// the fixture is larger than the limit.
+	transport := newCacheTransport(srv.Client().Transport, dir, time.Hour, time.Minute, false, false, 1<<10)
+	c := NewClient(WithBaseURL(srv.URL), WithHTTPClient(&http.Client{Transport: transport}))
+
+	if _, err := c.readOverview(context.Background(), "Fugazi"); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("read_overview: expected %v, got %v", ErrBodyTooLarge, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "music_Fugazi.http")); !os.IsNotExist(err) {
+		t.Fatalf("cache: expected the page not cached, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/url"
+	"slices"
+
+	"github.com/oiweiwei/lastfmq/htmlq"
+	"golang.org/x/net/html"
+)
+
+// readChartArtists function returns the band slugs of the music charts page, the top
+// artists and the artists of the top tracks, in the chart order.
+func (c *Client) readChartArtists(ctx context.Context) ([]string, error) {
+
+	resp, err := c.fetch(ctx, chartsPath)
+	if err != nil {
+		return nil, fmt.Errorf("read_charts: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	tokenizer := html.NewTokenizer(resp.Body)
+
+	var (
+		slugs     []string
+		startName bool
+	)
+
+	for tok := tokenizer.Next(); tokenizer.Err() == nil; tok = tokenizer.Next() {
+
+		switch tok {
+		case html.EndTagToken:
+			if startName && htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("td", "")) != "" {
+				startName = false
+			}
+		case html.StartTagToken:
+
+			switch attr := htmlq.ContainsAttr(tokenizer,
+				htmlq.TagAttr("td", "class", "globalchart-name", "globalchart-track-artist-name"),
+				htmlq.TagAttr("a", "href", "*")); attr {
+			case "globalchart-name", "globalchart-track-artist-name":
+				startName = true
+			case "":
+				// noop.
+			default:
+
+				if !startName {
+					continue
+				}
+
+				u, err := url.Parse(attr)
+				if err != nil {
+					continue
+				}
+
+				// the track links are /music/<band_name>/_/<track_name>.
+				if slug, err := bandSlug(u); err == nil && !slices.Contains(slugs, slug) {
+					slugs = append(slugs, slug)
+				}
+			}
+		}
+	}
+
+	if err := tokenizer.Err(); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("read_charts: tokenizer: %w", err)
+	}
+
+	return slugs, nil
+}
+
+// readRandomArtist function returns the random band slug from the music charts page
+// (-random), the chosen band is logged to stderr.
+func (c *Client) readRandomArtist(ctx context.Context) (string, error) {
+
+	slugs, err := c.readChartArtists(ctx)
+	if err != nil {
+		return "", fmt.Errorf("random: %w", err)
+	}
+
+	if len(slugs) == 0 {
+		return "", fmt.Errorf("random: %w: no chart artists", ErrBandNotFound)
+	}
+
+	slug := slugs[rand.IntN(len(slugs))]
+
+	log.Printf("random: using %q", slug)
+
+	return slug, nil
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// defaultBaseURL is the last.fm website base url.
+const defaultBaseURL = "https://www.last.fm"
+
+// Config is the set of the client reading settings, so the concurrent reads with
+// different settings don't interfere.
+type Config struct {
+	// RefFormat is the format for the wiki references in text.
+	RefFormat string
+	// WikiCompact adds the wiki bio as one line to the structured bio.
+	WikiCompact bool
+	// Workers is the number of workers for concurrent sections and pages, the
+	// non-positive number is a single worker.
+	Workers int
+	// SimilarArtistsPages and SimilarArtistsOffset are the similar artists pages to read.
+	SimilarArtistsPages, SimilarArtistsOffset int
+	// TagsPages, AlbumsPages and TracksPages are the numbers of pages to read.
+	TagsPages, AlbumsPages, TracksPages int
+	// AlbumsSort is the top albums order: year or the page order if not set.
+	AlbumsSort string
+	// MaxTracks is the maximum number of top tracks (no limit if zero).
+	MaxTracks int
+	// Links keeps the top albums and tracks external buy and stream links.
+	Links bool
+	// EventsUpcoming and EventsPast select the upcoming or past events only.
+	EventsUpcoming, EventsPast bool
+	// EventsSince and EventsUntil (inclusive) limit the events dates if set.
+	EventsSince, EventsUntil time.Time
+	// WikiTimeout, TagsTimeout, etc. limit the sections over the read context if set.
+	WikiTimeout, TagsTimeout, SimilarArtistsTimeout time.Duration
+	EventsTimeout, AlbumsTimeout, TracksTimeout     time.Duration
+	// PageTimeout limits each similar artists page read, the timed out page is read
+	// once more (no timeout if zero).
+	PageTimeout time.Duration
+	// RetryOnEmpty re-reads the empty tags, similar artists and events sections once.
+	RetryOnEmpty bool
+	// SectionRetries re-reads the failed or empty sections, after a short delay, up to
+	// the number of times (at most 10).
+	SectionRetries int
+	// SimilarMatch keeps the similar artists match percent, listeners and image.
+	SimilarMatch bool
+	// SimilarMinMatch drops the similar artists with the match below the fraction,
+	// e.g. 0.8, and the artists with unknown match (no filter if zero).
+	SimilarMinMatch float64
+	// OverviewSimilar adds the top similar artists from the overview page sidebar, the
+	// similar artists section and the tags page sidebar take precedence.
+	OverviewSimilar bool
+	// Sort is the tags and similar artists order: name, count or match, the page
+	// order is kept if not set. The tags are sorted by name only.
+	Sort string
+	// BestEffort turns the section errors into the band warnings, the overview
+	// is still required.
+	BestEffort bool
+	// PagesConcurrent is the number of workers for the tags, albums and tracks
+	// pages, the pages are read serially if not set.
+	PagesConcurrent int
+	// IncludeSources collects the section page urls into the band _sources.
+	IncludeSources bool
+	// Cookies are attached to every request, e.g. to pin the region.
+	Cookies []*http.Cookie
+	// UserAgents are used in turn for the requests, the default user agent is
+	// used if not set.
+	UserAgents []string
+	// Headers are attached to every request, and replace the user agent and the
+	// cookies only if set explicitly.
+	Headers http.Header
+	// MaxBodyBytes fails the page reads past the size with ErrBodyTooLarge (no limit
+	// if zero).
+	MaxBodyBytes int64
+	// Verbose logs the resolved names, the section retries and the pages read.
+	Verbose bool
+	// Resolve resolves the band names to the top search result.
+	Resolve bool
+	// Search and MBID make the dry run print the search or the MusicBrainz id lookup
+	// url instead of the band pages.
+	Search bool
+	MBID   string
+	// BatchWorkers is the number of the bands read concurrently in batch mode.
+	BatchWorkers int
+	// Ordered writes the batch records in the input order.
+	Ordered bool
+	// BatchArray writes the batch records as one json array.
+	BatchArray bool
+	// OutputDir writes each batch record into the band file in the directory, the
+	// existing file is kept if NoOverwrite is set.
+	OutputDir   string
+	NoOverwrite bool
+	// MinListeners skips the batch bands with fewer listeners (no filter if zero).
+	MinListeners int
+	// SQLite saves the band records into the database instead of writing them if set.
+	SQLite *sqliteStore
+	// Format is the band output format: json if not set, text or template.
+	Format string
+	// Template is the template for the template format.
+	Template *template.Template
+	// Fields are the band description fields to write, all the fields if not set.
+	Fields []string
+	// Bare writes the data of the only enabled section.
+	Bare bool
+	// IncludeEmpty writes the empty band description fields too.
+	IncludeEmpty bool
+	// Pretty indents the json output.
+	Pretty bool
+	// Color colorizes the text output.
+	Color bool
+}
+
+// DefaultMaxBodyBytes is the default page size limit.
+const DefaultMaxBodyBytes = 8 << 20
+
+// DefaultConfig function returns the default settings, same as the command-line defaults.
+func DefaultConfig() Config {
+	return Config{
+		RefFormat:           "%q",
+		Workers:             1,
+		BatchWorkers:        1,
+		SimilarArtistsPages: 5,
+		TagsPages:           1,
+		AlbumsPages:         1,
+		TracksPages:         1,
+		PageTimeout:         15 * time.Second,
+		MaxBodyBytes:        DefaultMaxBodyBytes,
+	}
+}
+
+// Client reads the band information from the last.fm website.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	cfg        Config
+	// userAgent is the number of the requests with the rotated user agent.
+	userAgent atomic.Uint64
+	// requestHook is called after each request if set.
+	requestHook func(RequestInfo)
+}
+
+// RequestInfo is the last.fm request metadata passed to the request hook.
+type RequestInfo struct {
+	// URL is the requested page url, the redirects are followed within the request.
+	URL string
+	// StatusCode is the final response status, zero if the request failed.
+	StatusCode int
+	// Duration is the time until the response headers are read, including the
+	// redirects and the retries.
+	Duration time.Duration
+	// Err is the request error if any, the non-200 responses are not errors.
+	Err error
+}
+
+// Option configures the client.
+type Option func(*Client)
+
+// WithBaseURL option sets the base url the page paths are resolved against.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) { c.baseURL = strings.TrimSuffix(baseURL, "/") }
+}
+
+// WithConfig option sets the reading settings.
+func WithConfig(cfg Config) Option {
+	return func(c *Client) { c.cfg = cfg }
+}
+
+// WithHTTPClient option sets the http client used to fetch the pages.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithRequestHook option sets the function called after each page request with the
+// status and the duration, e.g. to observe the last.fm health. The hook is called
+// concurrently from the section workers.
+func WithRequestHook(hook func(RequestInfo)) Option {
+	return func(c *Client) { c.requestHook = hook }
+}
+
+// TransportConfig is the connection reuse settings of the http transport.
+type TransportConfig struct {
+	// MaxIdleConnsPerHost is the number of idle connections kept to last.fm, the
+	// default is the number of workers.
+	MaxIdleConnsPerHost int
+	// ForceAttemptHTTP2 enables HTTP/2 for the transport.
+	ForceAttemptHTTP2 bool
+	// IdleConnTimeout is the keep-alive timeout for the idle connections.
+	IdleConnTimeout time.Duration
+}
+
+// newTransport function returns the default transport tuned with the settings.
+func newTransport(cfg TransportConfig) *http.Transport {
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+		transport.MaxIdleConns = max(transport.MaxIdleConns, cfg.MaxIdleConnsPerHost)
+	}
+
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+
+	transport.ForceAttemptHTTP2 = cfg.ForceAttemptHTTP2
+
+	return transport
+}
+
+// WithTransport option sets the http client with the transport tuned for the settings,
+// the idle connections default to the number of workers, so should go after WithConfig.
+func WithTransport(cfg TransportConfig) Option {
+	return func(c *Client) {
+		if cfg.MaxIdleConnsPerHost <= 0 {
+			cfg.MaxIdleConnsPerHost = c.cfg.Workers
+		}
+		c.httpClient = &http.Client{Timeout: defaultClient.Timeout, Transport: newTransport(cfg)}
+	}
+}
+
+// NewClient function returns the client that uses the default http client, the
+// last.fm base url and the default settings unless overridden with the options.
+func NewClient(opts ...Option) *Client {
+
+	c := &Client{httpClient: defaultClient, baseURL: defaultBaseURL, cfg: DefaultConfig()}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// zero workers would never read the pages.
+	c.cfg.Workers = max(1, c.cfg.Workers)
+
+	return c
+}
+
+// url function returns the page url for the path format and arguments.
+func (c *Client) url(format string, args ...any) string {
+	return c.baseURL + fmt.Sprintf(format, args...)
+}
+
+// do function sends the request and reports its status and duration to the request
+// hook if set.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+
+	if c.requestHook == nil {
+		return c.send(req)
+	}
+
+	start := time.Now()
+
+	resp, err := c.send(req)
+
+	info := RequestInfo{URL: req.URL.String(), Duration: time.Since(start), Err: err}
+	if resp != nil {
+		info.StatusCode = resp.StatusCode
+	}
+
+	c.requestHook(info)
+
+	return resp, err
+}
+
+// send function sends the request and records the final url, after the redirects, as
+// the section source if the sources are collected for the request context.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	recordSource(req.Context(), resp.Request.URL)
+
+	return resp, nil
+}
+
+// fetch function requests the page for the path format and arguments and returns the
+// response if the status is 200 OK, otherwise the *StatusError. The caller must close
+// the response body.
+func (c *Client) fetch(ctx context.Context, format string, args ...any) (*http.Response, error) {
+
+	req, err := c.newRequest(ctx, format, args...)
+	if err != nil {
+		return nil, fmt.Errorf("new_request_with_context: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http_get: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, newStatusError(resp)
+	}
+
+	// the consent page is small, so its marker is in the peeked head.
+	body := bufio.NewReaderSize(resp.Body, consentPeekSize)
+	if head, _ := body.Peek(consentPeekSize); isConsentPage(head) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrConsentRequired, resp.Request.URL)
+	}
+
+	var r io.Reader = body
+	if c.cfg.MaxBodyBytes > 0 {
+		r = &limitReader{Reader: body, max: c.cfg.MaxBodyBytes}
+	}
+
+	resp.Body = &contextBody{ReadCloser: readCloser{r, resp.Body}, ctx: ctx}
+
+	return resp, nil
+}
+
+// consentPeekSize is the size of the page head checked for the consent page markers.
+const consentPeekSize = 16 << 10
+
+// consentMarkers are the markup of the cookie consent interstitial page.
+var consentMarkers = [][]byte{
+	[]byte("consent-interstitial"),
+	[]byte(`action="/consent"`),
+}
+
+// isConsentPage function reports whether the page is the cookie consent interstitial.
+func isConsentPage(page []byte) bool {
+	for _, marker := range consentMarkers {
+		if bytes.Contains(page, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// readCloser reads from the reader and closes the closer, e.g. the buffered response body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// limitReader fails the reads past the max bytes with ErrBodyTooLarge, so the huge
+// page is not read forever.
+type limitReader struct {
+	io.Reader
+	max, read int64
+}
+
+func (r *limitReader) Read(p []byte) (int, error) {
+
+	// the reads after the overflow fail as well, without reading the body.
+	if r.read > r.max {
+		return 0, fmt.Errorf("%w: %d bytes", ErrBodyTooLarge, r.max)
+	}
+
+	n, err := r.Reader.Read(p)
+	if r.read += int64(n); r.read > r.max {
+		return n - int(r.read-r.max), fmt.Errorf("%w: %d bytes", ErrBodyTooLarge, r.max)
+	}
+
+	return n, err
+}
+
+// contextBody fails the reads once the context is done, so the tokenizer loops stop
+// with the context error on cancel, even if the page is already buffered, e.g. cached.
+type contextBody struct {
+	io.ReadCloser
+	ctx context.Context
+}
+
+func (b *contextBody) Read(p []byte) (int, error) {
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return b.ReadCloser.Read(p)
+}
+
+// Get function fetches the last.fm page by the path, e.g. "/music/Fugazi/+tags?page=2",
+// with the client cookies, user agents and the http client transports (rate limit,
+// retries, etc.), and returns the page body to parse, e.g. with the htmlq package.
+// The non-200 responses are returned as the *StatusError. The caller must close the
+// returned body.
+func (c *Client) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("get: path %q must start with /", path)
+	}
+
+	resp, err := c.fetch(ctx, "%s", path)
+	if err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+
+	return resp.Body, nil
+}
+
+// newRequest function returns the page request for the path format and arguments
+// with the configured cookies, user agent and headers.
+func (c *Client) newRequest(ctx context.Context, format string, args ...any) (*http.Request, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url(format, args...), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cookie := range c.cfg.Cookies {
+		req.AddCookie(cookie)
+	}
+
+	if n := len(c.cfg.UserAgents); n > 0 {
+		req.Header.Set("User-Agent", c.cfg.UserAgents[(c.userAgent.Add(1)-1)%uint64(n)])
+	}
+
+	for key, values := range c.cfg.Headers {
+		req.Header[key] = values
+	}
+
+	return req, nil
+}
+
+// readUserAgents function reads the -user-agent-file user agents, one per line, skipping
+// the empty lines and the # comments.
+func readUserAgents(path string) ([]string, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read_user_agents: %w", err)
+	}
+
+	defer f.Close()
+
+	userAgents, err := readBatch(f)
+	if err != nil {
+		return nil, fmt.Errorf("read_user_agents: %w", err)
+	}
+
+	if len(userAgents) == 0 {
+		return nil, fmt.Errorf("read_user_agents: no user agents in %s", path)
+	}
+
+	return userAgents, nil
+}
+
+// cookieFlag function returns the flag function that appends the name=value cookie.
+func cookieFlag(cookies *[]*http.Cookie) func(string) error {
+	return func(s string) error {
+
+		name, value, ok := strings.Cut(s, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid cookie %q, expected name=value", s)
+		}
+
+		cookie := &http.Cookie{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)}
+		if err := cookie.Valid(); err != nil {
+			return fmt.Errorf("invalid cookie %q: %w", s, err)
+		}
+
+		*cookies = append(*cookies, cookie)
+		return nil
+	}
+}
+
+// headerFlag function returns the flag function that adds the "Key: Value" header, the
+// repeated keys have multiple values.
+func headerFlag(headers *http.Header) func(string) error {
+	return func(s string) error {
+
+		key, value, ok := strings.Cut(s, ":")
+		if key, value = strings.TrimSpace(key), strings.TrimSpace(value); !ok || !isHeaderKey(key) {
+			return fmt.Errorf("invalid header %q, expected Key: Value", s)
+		}
+
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return fmt.Errorf("invalid header %q: invalid value", s)
+		}
+
+		if *headers == nil {
+			*headers = make(http.Header)
+		}
+
+		headers.Add(key, value)
+		return nil
+	}
+}
+
+// isHeaderKey function reports whether the key is the valid header name token.
+func isHeaderKey(key string) bool {
+	return key != "" && strings.Trim(key, "!#$%&'*+-.^_`|~0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithTransport(t *testing.T) {
+
+	cfg := DefaultConfig()
+	cfg.Workers = 8
+
+	c := NewClient(WithConfig(cfg), WithTransport(TransportConfig{ForceAttemptHTTP2: true}))
+
+	transport, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("with_transport: unexpected transport %T", c.httpClient.Transport)
+	}
+
+	if transport.MaxIdleConnsPerHost != cfg.Workers || !transport.ForceAttemptHTTP2 {
+		t.Fatalf("with_transport: expected %d idle connections with http2, got %d, %t",
+			cfg.Workers, transport.MaxIdleConnsPerHost, transport.ForceAttemptHTTP2)
+	}
+}
+
+func TestCookies(t *testing.T) {
+
+	var cookies []*http.Cookie
+
+	set := cookieFlag(&cookies)
+
+	for _, s := range []string{"lfmregion=DE", "consent = yes"} {
+		if err := set(s); err != nil {
+			t.Fatalf("cookie_flag: %v", err)
+		}
+	}
+
+	for _, s := range []string{"", "region", "=DE", "bad name=DE"} {
+		if err := set(s); err == nil {
+			t.Fatalf("cookie_flag: expected error for %q", s)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.Cookies = cookies
+
+	req, err := NewClient(WithConfig(cfg)).newRequest(context.Background(), overviewPath, "Fugazi")
+	if err != nil {
+		t.Fatalf("new_request: %v", err)
+	}
+
+	if actual := req.Header.Get("Cookie"); actual != "lfmregion=DE; consent=yes" {
+		t.Fatalf("new_request: unexpected cookie header %q", actual)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+
+	var headers http.Header
+
+	set := headerFlag(&headers)
+
+	for _, s := range []string{"X-Debug: 1", "x-debug:2", "User-Agent: custom/1.0", "X-Empty:"} {
+		if err := set(s); err != nil {
+			t.Fatalf("header_flag: %v", err)
+		}
+	}
+
+	for _, s := range []string{"", "X-Debug", ": 1", "Bad Key: 1", "X-Debug: a\nb"} {
+		if err := set(s); err == nil {
+			t.Fatalf("header_flag: expected error for %q", s)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.Headers = headers
+	cfg.UserAgents = []string{"agent/1"}
+	cfg.Cookies = []*http.Cookie{{Name: "lfmregion", Value: "DE"}}
+
+	req, err := NewClient(WithConfig(cfg)).newRequest(context.Background(), overviewPath, "Fugazi")
+	if err != nil {
+		t.Fatalf("new_request: %v", err)
+	}
+
+	if actual := req.Header.Values("X-Debug"); !slices.Equal(actual, []string{"1", "2"}) {
+		t.Fatalf("new_request: unexpected x-debug header %q", actual)
+	}
+
+	if actual := req.Header.Get("User-Agent"); actual != "custom/1.0" {
+		t.Fatalf("new_request: unexpected user agent %q", actual)
+	}
+
+	if actual := req.Header.Get("Cookie"); actual != "lfmregion=DE" {
+		t.Fatalf("new_request: unexpected cookie header %q", actual)
+	}
+}
+
+func TestUserAgents(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "agents.txt")
+	if err := os.WriteFile(path, []byte("# agents\nagent/1\n\nagent/2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	userAgents, err := readUserAgents(path)
+	if err != nil {
+		t.Fatalf("read_user_agents: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.UserAgents = userAgents
+
+	c := NewClient(WithConfig(cfg))
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = make(map[string]int)
+	)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := c.newRequest(context.Background(), overviewPath, "Fugazi")
+			if err != nil {
+				t.Errorf("new_request: %v", err)
+				return
+			}
+			mu.Lock()
+			seen[req.Header.Get("User-Agent")]++
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	if seen["agent/1"] != 5 || seen["agent/2"] != 5 {
+		t.Fatalf("new_request: expected the user agents in turn, got %v", seen)
+	}
+
+	req, err := NewClient().newRequest(context.Background(), overviewPath, "Fugazi")
+	if err != nil || req.Header.Get("User-Agent") != "" {
+		t.Fatalf("new_request: expected the default user agent, got %v", req.Header)
+	}
+}
+
+func BenchmarkTransport(b *testing.B) {
+
+	var conns atomic.Int64
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/similar_1.html")
+	}))
+
+	// count new connections to show the connection reuse.
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+
+	srv.Start()
+	defer srv.Close()
+
+	const workers = 16
+
+	for _, bc := range []struct {
+		name      string
+		transport *http.Transport
+	}{
+		{"default", http.DefaultTransport.(*http.Transport).Clone()},
+		{"tuned", newTransport(TransportConfig{MaxIdleConnsPerHost: workers})},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+
+			defer bc.transport.CloseIdleConnections()
+
+			client := &http.Client{Transport: bc.transport}
+
+			var wg sync.WaitGroup
+
+			conns.Store(0)
+			b.ResetTimer()
+
+			for i := 0; i < workers; i++ {
+
+				wg.Add(1)
+
+				go func(n int) {
+
+					defer wg.Done()
+
+					for ; n > 0; n-- {
+						resp, err := client.Get(srv.URL)
+						if err != nil {
+							b.Error(err)
+							return
+						}
+						io.Copy(io.Discard, resp.Body)
+						resp.Body.Close()
+						// the page is parsed while the connection is idle.
+						time.Sleep(100 * time.Microsecond)
+					}
+				}(b.N/workers + 1)
+			}
+
+			wg.Wait()
+
+			b.ReportMetric(float64(conns.Load())/float64(b.N), "conns/op")
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+
+	c := newFixtureClient(t, map[string]string{"/music/Fugazi/+tags?page=1": "tags.html"})
+
+	body, err := c.Get(context.Background(), "/music/Fugazi/+tags?page=1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	b, err := io.ReadAll(body)
+	if body.Close(); err != nil {
+		t.Fatalf("get: read: %v", err)
+	}
+
+	if !strings.Contains(string(b), "big-tags") {
+		t.Fatalf("get: unexpected body %q", b)
+	}
+
+	if _, err := c.Get(context.Background(), "/music/Nobody"); statusCode(err) != http.StatusNotFound {
+		t.Fatalf("get: expected not found status error, got %v", err)
+	}
+
+	if _, err := c.Get(context.Background(), "music/Fugazi"); err == nil {
+		t.Fatalf("get: expected error for the relative path")
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+
+	// the endless page.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html><body>")
+		for chunk := strings.Repeat("<p>big</p>", 1<<10); r.Context().Err() == nil; {
+			if _, err := io.WriteString(w, chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.MaxBodyBytes = 64 << 10
+
+	c := NewClient(WithBaseURL(srv.URL), WithHTTPClient(srv.Client()), WithConfig(cfg))
+
+	if _, err := c.readTags(context.Background(), "Fugazi"); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("read_tags: expected %v, got %v", ErrBodyTooLarge, err)
+	}
+
+	body, err := c.Get(context.Background(), "/music/Fugazi")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	defer body.Close()
+
+	if b, err := io.ReadAll(body); !errors.Is(err, ErrBodyTooLarge) || len(b) != 64<<10 {
+		t.Fatalf("get: expected %d bytes and %v, got %d, %v", 64<<10, ErrBodyTooLarge, len(b), err)
+	}
+}
+
+func TestLimitReader(t *testing.T) {
+
+	r := &limitReader{Reader: strings.NewReader(strings.Repeat("x", 100)), max: 10}
+
+	p := make([]byte, 8)
+
+	if n, err := r.Read(p); n != 8 || err != nil {
+		t.Fatalf("read: expected 8 bytes, got %d, %v", n, err)
+	}
+
+	if n, err := r.Read(p); n != 2 || !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("read: expected 2 bytes and %v, got %d, %v", ErrBodyTooLarge, n, err)
+	}
+
+	// the reads after the overflow return no bytes.
+	for i := 0; i < 2; i++ {
+		if n, err := r.Read(p); n != 0 || !errors.Is(err, ErrBodyTooLarge) {
+			t.Fatalf("read: expected 0 bytes and %v, got %d, %v", ErrBodyTooLarge, n, err)
+		}
+	}
+}
+
+func TestRequestHook(t *testing.T) {
+
+	var (
+		mu    sync.Mutex
+		infos []RequestInfo
+	)
+
+	c := newFixtureClient(t, map[string]string{"/music/Fugazi/+tags?page=1": "tags.html"}, WithRequestHook(func(info RequestInfo) {
+		mu.Lock()
+		defer mu.Unlock()
+		infos = append(infos, info)
+	}))
+
+	if _, err := c.readTags(context.Background(), "Fugazi"); err != nil {
+		t.Fatalf("read_tags: %v", err)
+	}
+
+	if _, err := c.Get(context.Background(), "/music/Nobody"); err == nil {
+		t.Fatalf("get: expected not found error")
+	}
+
+	if len(infos) != 2 {
+		t.Fatalf("request_hook: expected 2 requests, got %+v", infos)
+	}
+
+	if info := infos[0]; info.StatusCode != http.StatusOK || !strings.HasSuffix(info.URL, "/music/Fugazi/+tags?page=1") || info.Duration <= 0 || info.Err != nil {
+		t.Fatalf("request_hook: unexpected request %+v", info)
+	}
+
+	if info := infos[1]; info.StatusCode != http.StatusNotFound || info.Err != nil {
+		t.Fatalf("request_hook: unexpected request %+v", info)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+
+	t.Cleanup(srv.Close)
+
+	c := NewClient(WithBaseURL(srv.URL), WithHTTPClient(srv.Client()))
+
+	_, err := c.Get(context.Background(), "/music/Fugazi")
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("get: expected status error, got %v", err)
+	}
+
+	expected := "status: 429 Too Many Requests: " + srv.URL + "/music/Fugazi, Retry-After: 30, X-Ratelimit-Remaining: 0"
+	if actual := statusErr.Error(); actual != expected {
+		t.Fatalf("status_error: expected %q, got %q", expected, actual)
+	}
+
+	if actual := statusErr.Header().Get("Set-Cookie"); actual != "session=secret" {
+		t.Fatalf("status_error: expected full header, got %q", actual)
+	}
+}
+
+// cancelTransport responds with the page, the context is canceled after the first read.
+type cancelTransport struct {
+	page   string
+	cancel context.CancelFunc
+}
+
+func (t *cancelTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{},
+		Body:       io.NopCloser(&cancelReader{Reader: strings.NewReader(t.page), cancel: t.cancel}),
+		Request:    req,
+	}, nil
+}
+
+type cancelReader struct {
+	io.Reader
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	defer r.cancel()
+	// the small reads, so the page is not read at once.
+	return r.Reader.Read(p[:min(len(p), 64)])
+}
+
+func TestFetchCanceled(t *testing.T) {
+
+	page, err := os.ReadFile(filepath.Join("testdata", "overview.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := NewClient(WithHTTPClient(&http.Client{Transport: &cancelTransport{page: string(page), cancel: cancel}}))
+
+	if _, err := c.readOverview(ctx, "Fugazi"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("read_overview: expected canceled, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// dryRun function prints the urls that would be fetched for the bands and the sections
+// enabled with the flags (-dry-run), without any request to last.fm. The paged sections
+// are printed up to the configured number of pages, the read stops earlier at the last
+// page. In batch mode each band urls are preceded with the "# <band_name>" line.
+func (c *Client) dryRun(ctx context.Context, w io.Writer, names []string, with sections) error {
+
+	for i, name := range names {
+
+		if len(names) > 1 {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "# %s\n", name)
+		}
+
+		urls, err := c.bandURLs(ctx, name, with)
+		if err != nil {
+			return fmt.Errorf("dry_run: %s: %w", name, err)
+		}
+
+		if _, err := fmt.Fprintln(w, strings.Join(urls, "\n")); err != nil {
+			return fmt.Errorf("dry_run: write: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// bandURLs function returns the urls read for the band and the sections. The search is
+// printed instead of made for -resolve, so the band pages use the name as is.
+func (c *Client) bandURLs(ctx context.Context, bandName string, with sections) ([]string, error) {
+
+	var urls []string
+
+	add := func(format string, args ...any) error {
+		u, err := url.Parse(c.url(format, args...))
+		if err != nil {
+			return err
+		}
+		urls = append(urls, u.String())
+		return nil
+	}
+
+	// the band pages are known after the mbid redirect only.
+	if c.cfg.MBID != "" {
+		return urls, add(mbidPath, strings.ToLower(c.cfg.MBID))
+	}
+
+	if c.cfg.Search {
+		return urls, add(searchPath, url.QueryEscape(bandName))
+	}
+
+	switch {
+	case strings.HasPrefix(bandName, "https://"), strings.HasPrefix(bandName, "http://"):
+		// the url is resolved to the slug without the requests.
+		var err error
+		if bandName, err = c.resolveBand(ctx, bandName); err != nil {
+			return nil, err
+		}
+	case c.cfg.Resolve:
+		if err := add(searchPath, url.QueryEscape(bandName)); err != nil {
+			return nil, err
+		}
+	default:
+		// the ambiguous slug is searched for on read, here it is taken as is.
+		bandName, _ = nameSlug(bandName)
+	}
+
+	if err := add(overviewPath, bandName); err != nil {
+		return nil, err
+	}
+
+	for _, e := range extractors {
+
+		if !e.Enabled(with) {
+			continue
+		}
+
+		sectionURLs, err := e.URLs(c, bandName)
+		if err != nil {
+			return nil, err
+		}
+
+		urls = append(urls, sectionURLs...)
+	}
+
+	return urls, nil
+}
+
+// pageURL function returns the urls function of the single page section.
+func pageURL(format string) func(c *Client, bandName string) ([]string, error) {
+	return func(c *Client, bandName string) ([]string, error) {
+		u, err := url.Parse(c.url(format, bandName))
+		if err != nil {
+			return nil, err
+		}
+		return []string{u.String()}, nil
+	}
+}
+
+// pageURLs function returns the urls of the paged section, up to the configured
+// number of pages after the offset.
+func (c *Client) pageURLs(format, bandName string, pages, offset int) ([]string, error) {
+
+	var urls []string
+
+	for i := 1 + offset; i <= pages+offset; i++ {
+		u, err := url.Parse(c.url(format, bandName, i))
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, u.String())
+	}
+
+	return urls, nil
+}
+
+// runDryRun function prints the urls for the band or the batch file bands.
+func (c *Client) runDryRun(ctx context.Context, bandName, batch string, with sections) error {
+
+	names := []string{bandName}
+
+	if batch != "" {
+
+		f, err := openBatch(batch)
+		if err != nil {
+			return err
+		}
+
+		names, err = readBatch(f)
+		if f.Close(); err != nil {
+			return err
+		}
+	}
+
+	return c.dryRun(ctx, os.Stdout, names, with)
+}
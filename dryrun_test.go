@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+
+	cfg := DefaultConfig()
+	cfg.TagsPages, cfg.SimilarArtistsPages, cfg.SimilarArtistsOffset = 2, 1, 2
+
+	c := NewClient(WithBaseURL("https://www.last.fm"), WithConfig(cfg))
+
+	var b strings.Builder
+
+	names := []string{"Minor Threat", "https://www.last.fm/music/Sonic+Youth/+wiki"}
+
+	if err := c.dryRun(context.Background(), &b, names, sections{"tags": true, "similar-artists": true, "events": true}); err != nil {
+		t.Fatalf("dry_run: %v", err)
+	}
+
+	expected := `# Minor Threat
+https://www.last.fm/music/Minor+Threat
+https://www.last.fm/music/Minor+Threat/+tags?page=1
+https://www.last.fm/music/Minor+Threat/+tags?page=2
+https://www.last.fm/music/Minor+Threat/+similar?page=3
+https://www.last.fm/music/Minor+Threat/+events
+
+# https://www.last.fm/music/Sonic+Youth/+wiki
+https://www.last.fm/music/Sonic+Youth
+https://www.last.fm/music/Sonic+Youth/+tags?page=1
+https://www.last.fm/music/Sonic+Youth/+tags?page=2
+https://www.last.fm/music/Sonic+Youth/+similar?page=3
+https://www.last.fm/music/Sonic+Youth/+events
+`
+
+	if b.String() != expected {
+		t.Fatalf("dry_run: expected\n%s\ngot\n%s", expected, b.String())
+	}
+}
+
+func TestDryRunLookup(t *testing.T) {
+
+	for _, tc := range []struct {
+		name     string
+		cfg      func(*Config)
+		expected string
+	}{
+		{"mbid", func(cfg *Config) { cfg.MBID = "A2F3EB1A-1A2B-4C3D-8E4F-5A6B7C8D9E0F" }, "https://www.last.fm/mbid/a2f3eb1a-1a2b-4c3d-8e4f-5a6b7c8d9e0f\n"},
+		{"search", func(cfg *Config) { cfg.Search = true }, "https://www.last.fm/search/artists?q=Minor+Threat\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			cfg := DefaultConfig()
+			tc.cfg(&cfg)
+
+			var b strings.Builder
+
+			if err := NewClient(WithConfig(cfg)).dryRun(context.Background(), &b, []string{"Minor Threat"}, sections{}); err != nil {
+				t.Fatalf("dry_run: %v", err)
+			}
+
+			if b.String() != tc.expected {
+				t.Fatalf("dry_run: expected %q, got %q", tc.expected, b.String())
+			}
+		})
+	}
+}
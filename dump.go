@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dumpTransport writes the raw response bodies into the directory, one file per page,
+// while the body is being read by the tokenizer.
+type dumpTransport struct {
+	http.RoundTripper
+	dir string
+	// maxBodyBytes bounds the page written into the file (no limit if zero).
+	maxBodyBytes int64
+}
+
+func (t *dumpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(filepath.Join(t.dir, dumpFileName(req)))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	resp.Body = &dumpBody{Reader: io.TeeReader(resp.Body, f), body: resp.Body, f: f, max: t.maxBodyBytes}
+
+	return resp, nil
+}
+
+// dumpFileName function returns the file name for the request url path and query,
+// i.e. music_Fugazi_+similar_page=2.html.
+func dumpFileName(req *http.Request) string {
+
+	name := strings.Trim(req.URL.EscapedPath(), "/")
+	if req.URL.RawQuery != "" {
+		name += "_" + req.URL.RawQuery
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("._+=-%", r):
+			return r
+		}
+		return '_'
+	}, name) + ".html"
+}
+
+type dumpBody struct {
+	io.Reader
+	body      io.Closer
+	f         *os.File
+	max, read int64
+}
+
+func (b *dumpBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *dumpBody) Close() error {
+	// the parser may stop early, so read the rest of the page into the file, but not
+	// past the page size limit.
+	if b.max <= 0 {
+		io.Copy(io.Discard, b.Reader)
+	} else if b.read < b.max {
+		io.CopyN(io.Discard, b.Reader, b.max-b.read)
+	}
+	b.f.Close()
+	return b.body.Close()
+}
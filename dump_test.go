@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDumpTransport(t *testing.T) {
+
+	c := newFixtureClient(t, map[string]string{
+		"/music/Fugazi":              "overview.html",
+		"/music/Fugazi/+tags?page=1": "tags.html",
+	})
+
+	dir := t.TempDir()
+
+	c.httpClient = &http.Client{Transport: &dumpTransport{RoundTripper: c.httpClient.Transport, dir: dir}}
+
+	if _, err := c.readOverview(context.Background(), "Fugazi"); err != nil {
+		t.Fatalf("read_overview: %v", err)
+	}
+
+	if _, err := c.readTags(context.Background(), "Fugazi"); err != nil {
+		t.Fatalf("read_tags: %v", err)
+	}
+
+	// the whole page is dumped, even if the parser stops early.
+	for name, fixture := range map[string]string{
+		"music_Fugazi.html":              "overview.html",
+		"music_Fugazi_+tags_page=1.html": "tags.html",
+	} {
+
+		expected, err := os.ReadFile(filepath.Join("testdata", fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("dump: %v", err)
+		}
+
+		if !bytes.Equal(b, expected) {
+			t.Errorf("dump: %s: expected %s contents, got %d bytes", name, fixture, len(b))
+		}
+	}
+}
+
+func TestDumpTransportMaxBodyBytes(t *testing.T) {
+
+	// the endless page.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html><body>")
+		for chunk := strings.Repeat("<p>big</p>", 1<<10); r.Context().Err() == nil; {
+			if _, err := io.WriteString(w, chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	const maxBodyBytes = 64 << 10
+
+	cfg := DefaultConfig()
+	cfg.MaxBodyBytes = maxBodyBytes
+
+	dir := t.TempDir()
+
+	transport := &dumpTransport{RoundTripper: srv.Client().Transport, dir: dir, maxBodyBytes: maxBodyBytes}
+
+	c := NewClient(WithBaseURL(srv.URL), WithHTTPClient(&http.Client{Transport: transport}), WithConfig(cfg))
+
+	if _, err := c.readTags(context.Background(), "Fugazi"); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("read_tags: expected %v, got %v", ErrBodyTooLarge, err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "music_Fugazi_+tags_page=1.html"))
+	if err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+
+	// the rest of the page is not read past the limit on close.
+	if info.Size() < maxBodyBytes || info.Size() > 2*maxBodyBytes {
+		t.Fatalf("dump: expected about %d bytes, got %d", maxBodyBytes, info.Size())
+	}
+}
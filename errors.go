@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrBandNotFound is returned when last.fm has no page for the requested band.
+	ErrBandNotFound = errors.New("band not found")
+	// ErrRateLimited matches any status error with 429 Too Many Requests code.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrConsentRequired is returned when last.fm responds with the cookie consent page
+	// instead of the requested page, the consent cookie can be set with -cookie.
+	ErrConsentRequired = errors.New("cookie consent required")
+	// ErrCacheMiss is returned in the -offline mode for the page missing in the cache.
+	ErrCacheMiss = errors.New("not in cache")
+	// ErrBodyTooLarge is returned when the page is larger than the -max-body-bytes.
+	ErrBodyTooLarge = errors.New("body too large")
+)
+
+// StatusError is returned when last.fm responds with non-200 status code.
+type StatusError struct {
+	Code   int
+	URL    string
+	Status string
+	// Headers is the subset of the response headers useful for debugging, see
+	// statusHeaders.
+	Headers http.Header
+	header  http.Header
+}
+
+// statusHeaders is the list of the response headers kept in the status error, the
+// X-RateLimit-* headers are kept as well.
+var statusHeaders = []string{"Retry-After", "Content-Type"}
+
+func newStatusError(resp *http.Response) *StatusError {
+
+	headers := http.Header{}
+
+	for key, vals := range resp.Header {
+		if slices.Contains(statusHeaders, key) || strings.HasPrefix(key, "X-Ratelimit-") {
+			headers[key] = vals
+		}
+	}
+
+	return &StatusError{
+		Code:    resp.StatusCode,
+		URL:     resp.Request.URL.String(),
+		Status:  resp.Status,
+		Headers: headers,
+		header:  resp.Header,
+	}
+}
+
+// Header function returns the full response header.
+func (e *StatusError) Header() http.Header {
+	return e.header
+}
+
+func (e *StatusError) Error() string {
+
+	msg := fmt.Sprintf("status: %s: %s", e.Status, e.URL)
+
+	keys := slices.Sorted(maps.Keys(e.Headers))
+
+	for _, key := range keys {
+		msg += fmt.Sprintf(", %s: %s", key, strings.Join(e.Headers[key], ","))
+	}
+
+	return msg
+}
+
+// Is function allows to match the status error with errors.Is(err, ErrRateLimited).
+func (e *StatusError) Is(target error) bool {
+	return target == ErrRateLimited && e.Code == http.StatusTooManyRequests
+}
+
+// statusCode function returns the response status code of the *StatusError, or zero for
+// the other errors.
+func statusCode(err error) int {
+
+	var statusErr *StatusError
+
+	if errors.As(err, &statusErr) {
+		return statusErr.Code
+	}
+
+	return 0
+}
+
+const (
+	exitFailure      = 1
+	exitBandNotFound = 2
+	exitNetwork      = 3
+	exitRateLimited  = 4
+	exitCanceled     = 130
+)
+
+// exitCode function maps the error to the process exit code.
+func exitCode(err error) int {
+
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, ErrBandNotFound):
+		return exitBandNotFound
+	case errors.Is(err, ErrRateLimited):
+		return exitRateLimited
+	case errors.Is(err, context.Canceled):
+		return exitCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		return exitNetwork
+	}
+
+	return exitFailure
+}
+
+// exit function prints the error and terminates the process with matching exit code.
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	stats.failures.Add(1)
+	printStats()
+	os.Exit(exitCode(err))
+}
+
+// httpStatus function maps the error to the server mode response status code.
+func httpStatus(err error) int {
+
+	switch {
+	case errors.Is(err, ErrBandNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusBadGateway
+}
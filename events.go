@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/oiweiwei/lastfmq/htmlq"
+	"golang.org/x/net/html"
+)
+
+// readEvents function reads the events page once and returns the event years and the
+// events, both parsed from the same page.
+func (c *Client) readEvents(ctx context.Context, bandName string) (*eventsResult, error) {
+
+	if bandName == "" {
+		return nil, fmt.Errorf("read_events: band name is required")
+	}
+
+	resp, err := c.fetch(ctx, eventsPath, bandName)
+	if err != nil {
+		return nil, fmt.Errorf("read_events: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	// the page is parsed twice, for the years navigation and for the events list.
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read_events: %w", err)
+	}
+
+	years, err := parseEventYears(ctx, b)
+	if err != nil {
+		return nil, fmt.Errorf("read_events: years: tokenizer: %w", err)
+	}
+
+	events, err := c.parseEvents(ctx, b)
+	if err != nil {
+		return nil, fmt.Errorf("read_events: tokenizer: %w", err)
+	}
+
+	return &eventsResult{years: years, events: events}, nil
+}
+
+// parseEvents function parses the events list of the events page, merged with the
+// JSON-LD events and filtered by the events settings. The buffered page parse stops
+// with the context error once the context is done.
+func (c *Client) parseEvents(ctx context.Context, data []byte) ([]*Event, error) {
+
+	tokenizer := html.NewTokenizer(&contextBody{ReadCloser: io.NopCloser(bytes.NewReader(data)), ctx: ctx})
+
+	var (
+		events                       []*Event
+		upcoming, section, startDate bool
+		date                         []string
+		datetime                     string
+		ld                           []ldObject
+	)
+
+	for tok := tokenizer.Next(); tokenizer.Err() == nil; tok = tokenizer.Next() {
+
+		switch tok {
+		case html.EndTagToken:
+			if startDate && htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("td", "")) != "" {
+				if len(events) > 0 {
+					setEventDate(events[len(events)-1], strings.Join(date, " "), datetime, !section)
+				}
+				startDate, date, datetime = false, nil, ""
+			}
+		case html.TextToken:
+			if startDate {
+				if txt := htmlq.NormalizeSpace(string(tokenizer.Text())); txt != "" {
+					date = append(date, txt)
+				}
+			}
+		case html.StartTagToken:
+
+			if startDate {
+				if attr := htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("time", "datetime", "*")); attr != "" {
+					datetime = attr
+				}
+				continue
+			}
+
+			switch attr := htmlq.ContainsAttr(tokenizer,
+				htmlq.TagAttr("script", "type", "application/ld+json"),
+				htmlq.TagAttr("h3", ""),
+				htmlq.TagAttr("tr", "class", "events-list-item"),
+				htmlq.TagAttr("td", "class", "events-list-item-date"),
+				htmlq.TagAttr("p", "class", "events-list-item-event--lineup"),
+				htmlq.TagAttr("div", "class", "events-list-item-venue--title", "events-list-item-venue--address")); attr {
+
+			case "h3":
+
+				if tokenizer.Next() != html.TextToken {
+					continue
+				}
+
+				// the section heading decides whether the following events are upcoming or past.
+				switch txt := string(tokenizer.Text()); {
+				case strings.Contains(txt, "Upcoming"):
+					upcoming, section = true, true
+				case strings.Contains(txt, "Past"):
+					upcoming, section = false, true
+				}
+
+			case "application/ld+json":
+				if tokenizer.Next() == html.TextToken {
+					ld = append(ld, ldObjects(tokenizer.Text(), "MusicEvent", "Event")...)
+				}
+			case "events-list-item":
+				events = append(events, &Event{Address: &EventAddress{}, Upcoming: upcoming})
+			case "":
+				// noop.
+			default:
+
+				if len(events) == 0 {
+					continue
+				}
+
+				event := events[len(events)-1]
+
+				if attr == "events-list-item-date" {
+					startDate = true
+					continue
+				}
+
+				if tokenizer.Next() != html.TextToken {
+					continue
+				}
+
+				txt := htmlq.NormalizeSpace(string(tokenizer.Text()))
+
+				switch attr {
+				case "events-list-item-event--lineup":
+					event.Lineup = strings.TrimSpace(strings.TrimPrefix(txt, "Lineup:"))
+				case "events-list-item-venue--title":
+					event.Address.Name = txt
+				case "events-list-item-venue--address":
+					// address has the "Locality, Country" format.
+					event.Address.Locality, event.Address.Country = splitLocation(txt)
+				}
+			}
+		}
+	}
+
+	if err := tokenizer.Err(); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return c.filterEvents(mergeLDEvents(events, ld)), nil
+}
+
+// mergeLDEvents function updates the scraped events with the JSON-LD MusicEvent data,
+// matched by the venue name and the date, the structured data takes precedence. The
+// JSON-LD events are used as is if no events are scraped, the upcoming flag is derived
+// from the date then.
+func mergeLDEvents(events []*Event, ld []ldObject) []*Event {
+
+	if len(events) == 0 {
+		for _, obj := range ld {
+			event := ldEvent(obj)
+			if event.Upcoming = event.Date != nil && event.Date.After(time.Now()); event.Date == nil {
+				event.DateRaw = obj.string("startDate")
+			}
+			events = append(events, event)
+		}
+		return events
+	}
+
+	for _, obj := range ld {
+
+		src := ldEvent(obj)
+
+		for _, event := range events {
+
+			if event.Address.Name != src.Address.Name {
+				continue
+			}
+
+			if event.Date != nil && src.Date != nil && event.Date.Format(time.DateOnly) != src.Date.Format(time.DateOnly) {
+				continue
+			}
+
+			mergeEvent(event, src)
+		}
+	}
+
+	return events
+}
+
+// ldEvent function returns the event from the JSON-LD MusicEvent object.
+func ldEvent(obj ldObject) *Event {
+
+	var (
+		event    = &Event{Address: &EventAddress{}}
+		location = obj.object("location")
+		address  = location.object("address")
+		geo      = location.object("geo")
+	)
+
+	if t, ok := parseEventDate(obj.string("startDate")); ok {
+		event.Date = &t
+	}
+
+	event.Lineup = strings.Join(obj.names("performer"), ", ")
+
+	event.Address.Name = location.string("name")
+	event.Address.Street = address.string("streetAddress")
+	event.Address.Locality = address.string("addressLocality")
+	event.Address.Code = address.string("postalCode")
+	event.Address.Country = address.string("addressCountry")
+
+	lat, latOK := geo.float("latitude")
+	lon, lonOK := geo.float("longitude")
+	if latOK && lonOK {
+		event.Address.Lat, event.Address.Lon = lat, lon
+	}
+
+	return event
+}
+
+// mergeEvent function sets the event fields from the non-empty source fields.
+func mergeEvent(event, src *Event) {
+
+	if src.Date != nil {
+		event.Date, event.DateRaw = src.Date, ""
+	}
+
+	if src.Lineup != "" {
+		event.Lineup = src.Lineup
+	}
+
+	for _, field := range []struct{ dst, src *string }{
+		{&event.Address.Street, &src.Address.Street},
+		{&event.Address.Locality, &src.Address.Locality},
+		{&event.Address.Code, &src.Address.Code},
+		{&event.Address.Country, &src.Address.Country},
+	} {
+		if *field.src != "" {
+			*field.dst = *field.src
+		}
+	}
+
+	if src.Address.Lat != 0 || src.Address.Lon != 0 {
+		event.Address.Lat, event.Address.Lon = src.Address.Lat, src.Address.Lon
+	}
+}
+
+// eventDateLayouts is the list of known last.fm event date formats.
+var eventDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"Mon 2 Jan 2006 15:04",
+	"Mon 2 Jan 2006, 15:04",
+	"Mon 2 Jan 2006 3:04pm",
+	"Mon 2 Jan 2006",
+	"Monday 2 January 2006",
+	"2 Jan 2006",
+	"2 January 2006",
+	"Jan 2 2006",
+	"Jan 2, 2006",
+}
+
+// parseEventDate function parses the event date in one of the known formats.
+func parseEventDate(s string) (time.Time, bool) {
+
+	s = strings.Join(strings.Fields(s), " ")
+
+	for _, layout := range eventDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// setEventDate function sets the event date from the datetime attribute or the date text,
+// and keeps the raw date text if neither could be parsed. When byDate is set, the upcoming
+// flag is derived from the date, so events with unparseable dates are treated as past.
+func setEventDate(event *Event, raw, datetime string, byDate bool) {
+
+	t, ok := parseEventDate(datetime)
+	if !ok {
+		t, ok = parseEventDate(raw)
+	}
+
+	if !ok {
+		event.DateRaw = raw
+	} else {
+		event.Date = &t
+	}
+
+	if byDate {
+		event.Upcoming = ok && t.After(time.Now())
+	}
+}
+
+// filterEvents function filters the events by the upcoming/past and the dates range
+// settings (the -events-upcoming, -events-past, -events-since and -events-until flags).
+func (c *Client) filterEvents(events []*Event) []*Event {
+
+	cfg := &c.cfg
+
+	if cfg.EventsUpcoming == cfg.EventsPast && cfg.EventsSince.IsZero() && cfg.EventsUntil.IsZero() {
+		return events
+	}
+
+	ret := []*Event{}
+
+	for _, event := range events {
+		if cfg.EventsUpcoming != cfg.EventsPast && event.Upcoming != cfg.EventsUpcoming {
+			continue
+		}
+		if !c.inEventsRange(event) {
+			continue
+		}
+		ret = append(ret, event)
+	}
+
+	return ret
+}
+
+// inEventsRange function reports whether the event date is within the since and until
+// range. Events without parsed date are out of any range.
+func (c *Client) inEventsRange(event *Event) bool {
+
+	since, until := c.cfg.EventsSince, c.cfg.EventsUntil
+
+	if since.IsZero() && until.IsZero() {
+		return true
+	}
+
+	if event.Date == nil {
+		return false
+	}
+
+	if !since.IsZero() && event.Date.Before(since) {
+		return false
+	}
+
+	if !until.IsZero() && !event.Date.Before(until.AddDate(0, 0, 1)) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"strings"
+)
+
+// Extractor is the optional band section reader, e.g. the wiki or the top albums. The
+// enabled extractors are read after the overview, and each result is set into the band
+// description by the extractor that read it.
+type Extractor interface {
+	// Name returns the extractor name, same as the first section name.
+	Name() string
+	// Sections returns the sections read by the extractor, the section flags and the
+	// server query parameters are named after them.
+	Sections() []section
+	// Enabled reports whether any of the extractor sections is selected.
+	Enabled(with sections) bool
+	// Fetch reads the band section.
+	Fetch(ctx context.Context, c *Client, bandName string) (any, error)
+	// Set sets the Fetch result into the band description.
+	Set(c *Client, desc *bandDesc, v any, with sections)
+	// URLs returns the urls read by Fetch, for -dry-run.
+	URLs(c *Client, bandName string) ([]string, error)
+}
+
+// section is the optional band section, enabled with the flag of the same name.
+type section struct {
+	name  string
+	usage string
+	// bare returns the section data for -bare.
+	bare func(desc *bandDesc) any
+}
+
+// sections is the set of the enabled section names.
+type sections map[string]bool
+
+// extractor is the Extractor of the section functions.
+type extractor struct {
+	sections []section
+	fetch    func(ctx context.Context, c *Client, bandName string) (any, error)
+	set      func(c *Client, desc *bandDesc, v any, with sections)
+	urls     func(c *Client, bandName string) ([]string, error)
+}
+
+func (e *extractor) Name() string { return e.sections[0].name }
+
+func (e *extractor) Sections() []section { return e.sections }
+
+func (e *extractor) Enabled(with sections) bool {
+	for _, s := range e.sections {
+		if with[s.name] {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *extractor) Fetch(ctx context.Context, c *Client, bandName string) (any, error) {
+	return e.fetch(ctx, c, bandName)
+}
+
+func (e *extractor) Set(c *Client, desc *bandDesc, v any, with sections) {
+	e.set(c, desc, v, with)
+}
+
+func (e *extractor) URLs(c *Client, bandName string) ([]string, error) {
+	return e.urls(c, bandName)
+}
+
+// extractors is the registry of the band sections, the results are set in the registry
+// order.
+var extractors = []Extractor{
+	&extractor{
+		sections: []section{
+			{"wiki", "read wiki", func(desc *bandDesc) any { return desc.Wiki }},
+		},
+		fetch: fetchWiki,
+		set:   setWiki,
+		urls:  pageURL(wikiPath),
+	},
+	&extractor{
+		// the related tags are read from the tags pages.
+		sections: []section{
+			{"tags", "read artists tags", func(desc *bandDesc) any { return orEmpty(desc.Tags) }},
+			{"related-tags", "read related tags from the tags page", func(desc *bandDesc) any { return orEmpty(desc.RelatedTags) }},
+		},
+		fetch: fetchTags,
+		set:   setTags,
+		urls: func(c *Client, bandName string) ([]string, error) {
+			return c.pageURLs(tagsPagePath, bandName, c.cfg.TagsPages, 0)
+		},
+	},
+	&extractor{
+		sections: []section{
+			{"similar-artists", "read similar artists", bareSimilarArtists},
+		},
+		fetch: fetchSimilarArtists,
+		set:   setSimilarArtists,
+		urls: func(c *Client, bandName string) ([]string, error) {
+			return c.pageURLs(similarArtistsPagePath, bandName, c.cfg.SimilarArtistsPages, c.cfg.SimilarArtistsOffset)
+		},
+	},
+	&extractor{
+		sections: []section{
+			{"events", "read events", func(desc *bandDesc) any { return orEmpty(desc.Events) }},
+		},
+		fetch: fetchEvents,
+		set:   setEvents,
+		// the event years and the events are read from the same page.
+		urls: pageURL(eventsPath),
+	},
+	&extractor{
+		sections: []section{
+			{"albums", "read top albums", func(desc *bandDesc) any { return orEmpty(desc.TopAlbums) }},
+		},
+		fetch: fetchAlbums,
+		set:   setAlbums,
+		urls: func(c *Client, bandName string) ([]string, error) {
+			return c.pageURLs(albumsPagePath, bandName, c.cfg.AlbumsPages, 0)
+		},
+	},
+	&extractor{
+		sections: []section{
+			{"tracks", "read top tracks", func(desc *bandDesc) any { return orEmpty(desc.TopTracks) }},
+		},
+		fetch: fetchTracks,
+		set:   setTracks,
+		urls: func(c *Client, bandName string) ([]string, error) {
+			return c.pageURLs(tracksPagePath, bandName, c.cfg.TracksPages, 0)
+		},
+	},
+}
+
+// allSections function returns the sections of all the extractors in the registry order.
+func allSections() []section {
+
+	var ret []section
+
+	for _, e := range extractors {
+		ret = append(ret, e.Sections()...)
+	}
+
+	return ret
+}
+
+// sourceName function returns the band sources key of the section, i.e. similar_artists.
+func sourceName(e Extractor) string {
+	return strings.ReplaceAll(e.Name(), "-", "_")
+}
+
+// bareSection function returns the data of the only enabled section for -bare, the
+// missing lists are empty rather than null.
+func bareSection(desc *bandDesc, with sections) any {
+
+	for _, s := range allSections() {
+		if with[s.name] {
+			return s.bare(desc)
+		}
+	}
+
+	return desc
+}
+
+// orEmpty function returns the empty slice for nil.
+func orEmpty[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// isEmptySection function reports whether the section result has no data, the results
+// of the unknown sections are never empty.
+func isEmptySection(v any) bool {
+	switch v := v.(type) {
+	case *Wiki:
+		return v == nil || (len(v.Bio) == 0 && len(v.Members) == 0)
+	case *TagsResult:
+		return v == nil || (len(v.Tags) == 0 && len(v.TopTags) == 0)
+	case *similarResult:
+		return v == nil || len(v.similar) == 0
+	case *eventsResult:
+		return v == nil || (len(v.years) == 0 && len(v.events) == 0)
+	case []*Album:
+		return len(v) == 0
+	case []*Track:
+		return len(v) == 0
+	}
+	return false
+}
+
+func fetchWiki(ctx context.Context, c *Client, bandName string) (any, error) {
+
+	var wiki *Wiki
+
+	err := withTimeout(c.cfg.WikiTimeout, func(ctx context.Context) (err error) {
+		wiki, err = c.readWiki(ctx, bandName)
+		return
+	})(ctx)
+
+	return wiki, err
+}
+
+func setWiki(c *Client, desc *bandDesc, v any, with sections) {
+	if wiki, ok := v.(*Wiki); ok {
+		desc.Wiki = wiki
+	}
+}
+
+func fetchTags(ctx context.Context, c *Client, bandName string) (any, error) {
+
+	var page *TagsResult
+
+	err := withTimeout(c.cfg.TagsTimeout, func(ctx context.Context) error {
+		return c.retryOnEmpty(ctx, "read_tags", func(ctx context.Context) (n int, err error) {
+			if page, err = c.readTags(ctx, bandName); err != nil {
+				return 0, err
+			}
+			return len(page.Tags), nil
+		})
+	})(ctx)
+
+	return page, err
+}
+
+func setTags(c *Client, desc *bandDesc, v any, with sections) {
+
+	page, ok := v.(*TagsResult)
+	if !ok {
+		return
+	}
+
+	// the empty sidebar keeps the overview similar artists (-overview-similar).
+	if len(page.SimilarArtists) > 0 {
+		desc.SimilarArtists = page.SimilarArtists
+	}
+
+	if with["tags"] {
+		desc.TopTags, desc.Tags = page.TopTags, page.Tags
+	}
+
+	if with["related-tags"] {
+		desc.RelatedTags = page.RelatedTags
+	}
+
+	if genre := page.genre(); genre != "" {
+		desc.Genre = genre
+	}
+}
+
+// similarResult is the similar artists section, the artists and the total number of the
+// similar artists, if known.
+type similarResult struct {
+	similar []*SimilarArtist
+	total   int
+}
+
+func fetchSimilarArtists(ctx context.Context, c *Client, bandName string) (any, error) {
+
+	ret := &similarResult{}
+
+	readSimilarArtists := c.readSimilarArtists
+	if c.cfg.Workers > 1 {
+		readSimilarArtists = c.readSimilarArtistsAsync
+	}
+
+	err := withTimeout(c.cfg.SimilarArtistsTimeout, func(ctx context.Context) error {
+		return c.retryOnEmpty(ctx, "read_similar_artists", func(ctx context.Context) (n int, err error) {
+			ret.similar, ret.total, err = readSimilarArtists(ctx, bandName, c.cfg.SimilarArtistsPages, c.cfg.SimilarArtistsOffset)
+			return len(ret.similar), err
+		})
+	})(ctx)
+
+	return ret, err
+}
+
+// setSimilarArtists function sets the similar artists, the section takes precedence over
+// the tags page sidebar.
+func setSimilarArtists(c *Client, desc *bandDesc, v any, with sections) {
+
+	result, ok := v.(*similarResult)
+	if !ok {
+		return
+	}
+
+	desc.SimilarTotal = result.total
+	similar := filterSimilar(result.similar, c.cfg.SimilarMinMatch)
+	sortSimilar(similar, c.cfg.Sort)
+	if desc.SimilarArtists = similarNames(similar); c.cfg.SimilarMatch {
+		desc.SimilarMatch = similar
+	}
+}
+
+// bareSimilarArtists function returns the similar artists with the match for -similar-match.
+func bareSimilarArtists(desc *bandDesc) any {
+	if desc.SimilarMatch != nil {
+		return desc.SimilarMatch
+	}
+	return orEmpty(desc.SimilarArtists)
+}
+
+// eventsResult is the events section, the event years and the events are read from
+// the same page.
+type eventsResult struct {
+	years  []string
+	events []*Event
+}
+
+func fetchEvents(ctx context.Context, c *Client, bandName string) (any, error) {
+
+	var ret *eventsResult
+
+	err := withTimeout(c.cfg.EventsTimeout, func(ctx context.Context) error {
+		return c.retryOnEmpty(ctx, "read_events", func(ctx context.Context) (n int, err error) {
+			if ret, err = c.readEvents(ctx, bandName); err != nil {
+				return 0, err
+			}
+			// the page is re-read if either the years or the events are empty.
+			return min(len(ret.years), len(ret.events)), nil
+		})
+	})(ctx)
+
+	return ret, err
+}
+
+func setEvents(c *Client, desc *bandDesc, v any, with sections) {
+	if events, ok := v.(*eventsResult); ok {
+		desc.Years, desc.Events = events.years, events.events
+	}
+}
+
+func fetchAlbums(ctx context.Context, c *Client, bandName string) (any, error) {
+
+	var albums []*Album
+
+	err := withTimeout(c.cfg.AlbumsTimeout, func(ctx context.Context) (err error) {
+		albums, err = c.readTopAlbums(ctx, bandName)
+		return
+	})(ctx)
+
+	return albums, err
+}
+
+func setAlbums(c *Client, desc *bandDesc, v any, with sections) {
+	if albums, ok := v.([]*Album); ok {
+		desc.TopAlbums = albums
+	}
+}
+
+func fetchTracks(ctx context.Context, c *Client, bandName string) (any, error) {
+
+	var tracks []*Track
+
+	err := withTimeout(c.cfg.TracksTimeout, func(ctx context.Context) (err error) {
+		tracks, err = c.readTopTracks(ctx, bandName)
+		return
+	})(ctx)
+
+	return tracks, err
+}
+
+func setTracks(c *Client, desc *bandDesc, v any, with sections) {
+	if tracks, ok := v.([]*Track); ok {
+		desc.TopTracks = tracks
+	}
+}
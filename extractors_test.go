@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestSectionFlags(t *testing.T) {
+
+	names := make(map[string]bool)
+
+	for _, s := range allSections() {
+		if names[s.name] {
+			t.Fatalf("extractors: duplicate section %q", s.name)
+		}
+		names[s.name] = true
+	}
+
+	for name, section := range sectionFlags {
+
+		if !names[name] {
+			t.Fatalf("section_flags: unknown section %q", name)
+		}
+
+		f := flag.Lookup(name)
+		if f == nil {
+			t.Fatalf("section_flags: no %q flag", name)
+		}
+
+		// the flag value is the section variable.
+		prev := *section
+		if err := f.Value.Set("true"); err != nil || !*section {
+			t.Fatalf("section_flags: %q flag is not bound to the section", name)
+		}
+		*section = prev
+	}
+
+	if len(sectionFlags) != len(names) {
+		t.Fatalf("section_flags: got %d flags, want %d", len(sectionFlags), len(names))
+	}
+}
+
+func TestExtractorsEnabled(t *testing.T) {
+
+	for _, tc := range []struct {
+		with sections
+		want []string
+	}{
+		{sections{}, nil},
+		{sections{"related-tags": true}, []string{"tags"}},
+		{sections{"tags": true, "related-tags": true, "tracks": true}, []string{"tags", "tracks"}},
+	} {
+
+		var got []string
+		for _, e := range extractors {
+			if e.Enabled(tc.with) {
+				got = append(got, e.Name())
+			}
+		}
+
+		if len(got) != len(tc.want) {
+			t.Fatalf("%v: got %v, want %v", tc.with, got, tc.want)
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Fatalf("%v: got %v, want %v", tc.with, got, tc.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package htmlq_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/oiweiwei/lastfmq/htmlq"
+	"golang.org/x/net/html"
+)
+
+// The example extracts the album release date, which is not read by lastfmq.
+func ExampleContainsAttr() {
+
+	page := `<dl class="catalogue-metadata">
+	<dt class="catalogue-metadata-heading">Length</dt>
+	<dd class="catalogue-metadata-description">10 tracks, 34:38</dd>
+	<dt class="catalogue-metadata-heading">Release Date</dt>
+	<dd class="catalogue-metadata-description"><span>15 March</span> 1993</dd>
+</dl>`
+
+	var heading string
+
+	tokenizer := html.NewTokenizer(strings.NewReader(page))
+
+	for tok := tokenizer.Next(); tokenizer.Err() == nil; tok = tokenizer.Next() {
+
+		if tok != html.StartTagToken {
+			continue
+		}
+
+		switch htmlq.ContainsAttr(tokenizer,
+			htmlq.TagAttr("dt", "class", "catalogue-metadata-heading"),
+			htmlq.TagAttr("dd", "class", "catalogue-metadata-description")) {
+		case "catalogue-metadata-heading":
+			heading = htmlq.ReadText(tokenizer, "dt")
+		case "catalogue-metadata-description":
+			if txt := htmlq.ReadText(tokenizer, "dd"); heading == "Release Date" {
+				fmt.Println(txt)
+			}
+		}
+	}
+
+	// Output: 15 March 1993
+}
+
+func ExampleNewIter() {
+
+	tokenizer := html.NewTokenizer(strings.NewReader(`<a href="/music/Fugazi" class="link-block-target" title="Fugazi">`))
+	tokenizer.Next()
+
+	for iter := htmlq.NewIter(tokenizer); iter.Next(); {
+		key, val := iter.Attrs()
+		fmt.Printf("%s=%s\n", key, val)
+	}
+
+	// Output:
+	// href=/music/Fugazi
+	// class=link-block-target
+	// title=Fugazi
+}
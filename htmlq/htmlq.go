@@ -0,0 +1,194 @@
+// Package htmlq provides the helpers to extract the data from the html pages with the
+// streaming tokenizer from golang.org/x/net/html, without building the document tree.
+//
+// The page is read in the tokenizer loop, and the tags of interest are matched against
+// the tag attributes right after the tokenizer returns the tag token:
+//
+//	for tok := tokenizer.Next(); tokenizer.Err() == nil; tok = tokenizer.Next() {
+//		if tok == html.StartTagToken {
+//			switch htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("a", "class", "link-block-target")) {
+//			case "link-block-target":
+//				name := htmlq.ReadText(tokenizer, "a")
+//			}
+//		}
+//	}
+package htmlq
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Tag is the tag name and attribute to match the tag token against.
+type Tag struct {
+	tagName  string
+	attrName string
+	attrVals []string
+}
+
+// TagAttr function returns the tag to match. The attribute name is optional, if omitted
+// any tag with the tag name matches. The attribute values are matched as substrings of
+// the attribute value, the single "*" value matches any value.
+func TagAttr(tagName, attrName string, attrVals ...string) *Tag {
+	return &Tag{tagName, attrName, attrVals}
+}
+
+// ContainsAttr function will return matched attribute value or token name (if attribute value is omitted).
+//
+// ContainsAttr must be called right after the tokenizer returned the StartTagToken,
+// EndTagToken or SelfClosingTagToken and only once for the token, since the tag name
+// and attributes are consumed from the tokenizer.
+func ContainsAttr(tokenizer *html.Tokenizer, tags ...*Tag) string {
+	attr, _ := MatchAttr(tokenizer, tags...)
+	return attr
+}
+
+// MatchAttr function is same as ContainsAttr, but also returns the attribute iterator,
+// which can be reset to read all the attributes of the matched tag. The iterator is nil
+// if the tag name doesn't match any of the tags.
+func MatchAttr(tokenizer *html.Tokenizer, tags ...*Tag) (string, *Iter) {
+
+	var (
+		tagName, hasAttr = tokenizer.TagName()
+		iter             *Iter
+	)
+
+	for _, tag := range tags {
+		if tag.tagName != string(tagName) {
+			continue
+		}
+
+		// the iterator is created only for the tags of interest.
+		if iter == nil {
+			iter = NewIter(tokenizer)
+		}
+
+		if tag.attrName == "" {
+			return tag.tagName, iter
+		}
+
+		// the tag without attributes may still match the next tag without attribute name.
+		if !hasAttr {
+			continue
+		}
+
+		// the attributes are read once and checked against each tag attribute.
+		for _, attr := range iter.readAll() {
+			if string(attr.key) != tag.attrName {
+				continue
+			}
+			if len(tag.attrVals) == 0 {
+				return tag.attrName, iter
+			}
+			if tag.attrVals[0] == "*" {
+				return string(attr.val), iter
+			}
+			for _, attrVal := range tag.attrVals {
+				if bytes.Contains(attr.val, []byte(attrVal)) {
+					return attrVal, iter
+				}
+			}
+		}
+	}
+	return "", iter
+}
+
+// ReadText function reads the text of the element including the nested tags until
+// the closing tag, with whitespace collapsed.
+func ReadText(tokenizer *html.Tokenizer, tagName string) string {
+
+	var (
+		txt   []string
+		depth = 1
+	)
+
+	for tok := tokenizer.Next(); tokenizer.Err() == nil; tok = tokenizer.Next() {
+		switch tok {
+		case html.StartTagToken:
+			if ContainsAttr(tokenizer, TagAttr(tagName, "")) != "" {
+				depth++
+			}
+		case html.EndTagToken:
+			if ContainsAttr(tokenizer, TagAttr(tagName, "")) != "" {
+				if depth--; depth == 0 {
+					return NormalizeSpace(strings.Join(txt, ""))
+				}
+			}
+		case html.TextToken:
+			txt = append(txt, string(tokenizer.Text()))
+		}
+	}
+
+	return NormalizeSpace(strings.Join(txt, ""))
+}
+
+// NormalizeSpace function trims the text and collapses the whitespace runs, including
+// the newlines, into single spaces.
+func NormalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Iter iterates over the attributes of the current tag, reading them from the
+// tokenizer lazily. The read attributes are kept, so the iterator can be reset and
+// iterated again, which is not possible with the tokenizer. The attributes refer to
+// the tokenizer buffer, so the iterator is valid until the next token is read.
+type Iter struct {
+	tokenizer *html.Tokenizer
+	pos       int
+	attrs     []attr
+	// buf is the initial storage for attrs, enough for most of the tags.
+	buf [4]attr
+}
+
+type attr struct {
+	key, val []byte
+}
+
+// NewIter function returns the iterator over the current tag attributes, same as
+// ContainsAttr it must be created right after the tag token is returned.
+func NewIter(tokenizer *html.Tokenizer) *Iter {
+	iter := &Iter{tokenizer: tokenizer, pos: -1}
+	iter.attrs = iter.buf[:0]
+	return iter
+}
+
+// read function reads the next attribute from the tokenizer.
+func (i *Iter) read() {
+	key, val, more := i.tokenizer.TagAttr()
+	if !more {
+		i.tokenizer = nil
+	}
+	// tag without attributes yields the empty key.
+	if len(key) > 0 {
+		i.attrs = append(i.attrs, attr{key, val})
+	}
+}
+
+// readAll function reads the remaining attributes from the tokenizer and returns all
+// the attributes of the tag. The iterator position is not changed.
+func (i *Iter) readAll() []attr {
+	for i.tokenizer != nil {
+		i.read()
+	}
+	return i.attrs
+}
+
+// Next function advances the iterator to the next attribute.
+func (i *Iter) Next() bool {
+	if i.pos++; i.pos >= len(i.attrs) && i.tokenizer != nil {
+		i.read()
+	}
+	return i.pos < len(i.attrs)
+}
+
+// Reset function moves the iterator before the first attribute.
+func (i *Iter) Reset() {
+	i.pos = -1
+}
+
+// Attrs function returns the current attribute key and value.
+func (i *Iter) Attrs() (string, string) {
+	return string(i.attrs[i.pos].key), string(i.attrs[i.pos].val)
+}
@@ -0,0 +1,141 @@
+package htmlq
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// largePage function returns the similar artists fixture with the artists list repeated n times.
+func largePage(b *testing.B, n int) []byte {
+
+	page, err := os.ReadFile("../testdata/similar_1.html")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	start, end := bytes.Index(page, []byte("<li ")), bytes.LastIndex(page, []byte("</li>"))+len("</li>")
+
+	var buf bytes.Buffer
+	buf.Write(page[:start])
+	for i := 0; i < n; i++ {
+		buf.Write(page[start:end])
+	}
+	buf.Write(page[end:])
+
+	return buf.Bytes()
+}
+
+func TestContainsAttr(t *testing.T) {
+
+	tagAttrs := []*Tag{
+		TagAttr("ol", "class", "similar-artists"),
+		TagAttr("abbr", "title", "*"),
+		TagAttr("a", "rel", "external"),
+		TagAttr("a", "class", "link-block-target"),
+		TagAttr("a", "href"),
+		TagAttr("h3", ""),
+		TagAttr("abbr", ""),
+	}
+
+	for _, tc := range []struct {
+		element, expected string
+	}{
+		{`<ol class="similar-artists">`, "similar-artists"},
+		{`<ol class="big-tags">`, ""},
+		{`<abbr class="intabbr" title="751,721">`, "751,721"},
+		{`<abbr class="intabbr">`, "abbr"},
+		{`<abbr>`, "abbr"},
+		{`<a href="/music/Unwound" class="link-block-target">`, "link-block-target"},
+		{`<a class="link-block-target" rel="external nofollow">`, "external"},
+		{`<a href="/music/Unwound">`, "href"},
+		{`<a>`, ""},
+		{`<h3 class="similar-artists-item-name">`, "h3"},
+		{`<div class="similar-artists">`, ""},
+	} {
+
+		tokenizer := html.NewTokenizer(strings.NewReader(tc.element))
+		tokenizer.Next()
+
+		if attr := ContainsAttr(tokenizer, tagAttrs...); attr != tc.expected {
+			t.Errorf("contains_attr: %s: expected %q, got %q", tc.element, tc.expected, attr)
+		}
+	}
+}
+
+func TestReadText(t *testing.T) {
+
+	for _, tc := range []struct {
+		element, tagName, expected string
+	}{
+		{`<a class="link-block-target">Fugazi</a>`, "a", "Fugazi"},
+		{`<a class="link-block-target"><span>Minor</span> Threat</a>`, "a", "Minor Threat"},
+		{`<h1 class="header-new-title">  The <b>Ex</b>
+		</h1>`, "h1", "The Ex"},
+		{`<dd><a href="/place">Washington</a>, <span>United States</span></dd>`, "dd", "Washington, United States"},
+		{`<div><div>nested</div> div</div><p>after</p>`, "div", "nested div"},
+		{`<a class="link-block-target"></a>`, "a", ""},
+	} {
+
+		tokenizer := html.NewTokenizer(strings.NewReader(tc.element))
+		tokenizer.Next()
+
+		if txt := ReadText(tokenizer, tc.tagName); txt != tc.expected {
+			t.Errorf("read_text: %s: expected %q, got %q", tc.element, tc.expected, txt)
+		}
+	}
+}
+
+func BenchmarkContainsAttr(b *testing.B) {
+
+	page := largePage(b, 100)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+
+		tokenizer := html.NewTokenizer(bytes.NewReader(page))
+
+		for tok := tokenizer.Next(); tokenizer.Err() == nil; tok = tokenizer.Next() {
+			if tok != html.StartTagToken {
+				continue
+			}
+			ContainsAttr(tokenizer,
+				TagAttr("ol", "class", "similar-artists"),
+				TagAttr("li", "class", "similar-artists-item-wrap"),
+				TagAttr("a", "class", "link-block-target"))
+		}
+	}
+}
+
+func BenchmarkMatchAttrManyAttrs(b *testing.B) {
+
+	page := bytes.Repeat([]byte(`<a href="/music/Unwound" title="Unwound" rel="nofollow" itemprop="url" `+
+		`data-analytics-label="similar-artist" data-analytics-action="SimilarArtist" `+
+		`data-youtube-id="abc" target="_blank" class="link-block-target">Unwound</a>`), 1000)
+
+	tagAttrs := []*Tag{
+		TagAttr("a", "data-toggle", "dropdown"),
+		TagAttr("a", "aria-label", "*"),
+		TagAttr("a", "rel", "external"),
+		TagAttr("a", "class", "link-block-target"),
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+
+		tokenizer := html.NewTokenizer(bytes.NewReader(page))
+
+		for tok := tokenizer.Next(); tokenizer.Err() == nil; tok = tokenizer.Next() {
+			if tok == html.StartTagToken {
+				ContainsAttr(tokenizer, tagAttrs...)
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/oiweiwei/lastfmq/htmlq"
+)
+
+// ldObject is the JSON-LD (application/ld+json) structured data object.
+type ldObject map[string]any
+
+// ldObjects function returns the JSON-LD objects of the types, e.g. MusicGroup, from the
+// script data, including the objects in the arrays, the @graph and the nested objects.
+// The invalid data has no objects.
+func ldObjects(data []byte, types ...string) []ldObject {
+
+	var (
+		v   any
+		ret []ldObject
+	)
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil
+	}
+
+	var walk func(v any)
+
+	walk = func(v any) {
+		switch v := v.(type) {
+		case []any:
+			for _, v := range v {
+				walk(v)
+			}
+		case map[string]any:
+			if obj := ldObject(v); obj.is(types...) {
+				ret = append(ret, obj)
+			}
+			// the nested objects are walked in the keys order, so the result is stable.
+			for _, key := range slices.Sorted(maps.Keys(v)) {
+				walk(v[key])
+			}
+		}
+	}
+
+	walk(v)
+
+	return ret
+}
+
+// is function reports whether the object @type is one of the types, the @type may be
+// the list of types.
+func (o ldObject) is(types ...string) bool {
+	switch t := o["@type"].(type) {
+	case string:
+		return slices.Contains(types, t)
+	case []any:
+		for _, t := range t {
+			if t, ok := t.(string); ok && slices.Contains(types, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// object function returns the nested object by the key, the first one if it is a list.
+func (o ldObject) object(key string) ldObject {
+	switch v := o[key].(type) {
+	case map[string]any:
+		return v
+	case []any:
+		if len(v) > 0 {
+			if v, ok := v[0].(map[string]any); ok {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// string function returns the normalized string value by the key, the first one if it
+// is a list, or the url of the nested object, e.g. the ImageObject.
+func (o ldObject) string(key string) string {
+	switch v := o[key].(type) {
+	case string:
+		return htmlq.NormalizeSpace(v)
+	case []any:
+		if len(v) > 0 {
+			return ldObject{key: v[0]}.string(key)
+		}
+	case map[string]any:
+		return ldObject(v).string("url")
+	}
+	return ""
+}
+
+// names function returns the names of the nested objects by the key, e.g. the performers.
+func (o ldObject) names(key string) []string {
+
+	var ret []string
+
+	switch v := o[key].(type) {
+	case map[string]any:
+		if name := ldObject(v).string("name"); name != "" {
+			ret = append(ret, name)
+		}
+	case []any:
+		for _, v := range v {
+			if v, ok := v.(map[string]any); ok {
+				if name := ldObject(v).string("name"); name != "" {
+					ret = append(ret, name)
+				}
+			}
+		}
+	}
+
+	return ret
+}
+
+// float function returns the number value by the key, which may be a number or a string.
+func (o ldObject) float(key string) (float64, bool) {
+	switch v := o[key].(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
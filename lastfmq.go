@@ -1,31 +1,109 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
+	"syscall"
+	"text/template"
 	"time"
+	"unicode"
 
+	"github.com/oiweiwei/lastfmq/htmlq"
 	"golang.org/x/net/html"
 )
 
 var (
-	bandName                           string
-	refFormat                          string
-	tags, similarArtists, wiki, events bool
-	pageNum                            int
-	pageOffset                         int
-	workersNum                         int
+	bandName                   string
+	refFormat                  string
+	tagsPages                  int
+	pageNum                    int
+	pageOffset                 int
+	workersNum                 int
+	timeout                    time.Duration
+	pageTimeout                time.Duration
+	transportCfg               TransportConfig
+	maxConns                   int
+	maxBodyBytes               int64
+	retryEmpty                 bool
+	sectionRetries             int
+	similarMatch               bool
+	similarMinMatch            float64
+	overviewSimilar            bool
+	links                      bool
+	wikiCompact                bool
+	sortOrder                  string
+	albumsSort                 string
+	bestEffort                 bool
+	cookies                    []*http.Cookie
+	headers                    http.Header
+	retries, retryBudget       int
+	verbose                    bool
+	logJSON                    bool
+	albumsPages                int
+	tracksPages                int
+	maxTracks                  int
+	eventsUpcoming, eventsPast bool
+	eventsSince, eventsUntil   time.Time
+	all                        bool
+	fields                     []string
+	showStats                  bool
+	serveAddr                  string
+	search, resolve            bool
+	random                     bool
+	dumpHTML                   string
+	cacheDir                   string
+	cacheTTL, cacheNegativeTTL time.Duration
+	refresh                    bool
+	offline                    bool
+	dryRun                     bool
+	bare                       bool
+	format, color              string
+	templateFile               string
+	mbid                       string
+	pagesConcurrent            int
+	includeSources             bool
+	includeEmpty               bool
+	userAgentFile              string
+	proxyFile                  string
+	userAgents                 []string
+	outputTemplate             *template.Template
+	batch                      string
+	batchWorkers               int
+	ordered                    bool
+	batchArray                 bool
+	outputDir                  string
+	noOverwrite                bool
+	pretty                     bool
+	showVersion                bool
+	minListeners               int
+	sqlitePath                 string
+	fromNDJSONPath             string
 )
 
+// sectionFlags is the set of the section flags by the section name, e.g. -wiki.
+var sectionFlags = make(map[string]*bool)
+
+// sectionTimeouts is the set of the -wiki-timeout, -tags-timeout, etc. flags.
+var sectionTimeouts struct {
+	wiki, tags, similarArtists, events, albums, tracks time.Duration
+}
+
 var defaultClient = &http.Client{
 	// CheckRedirect: func(req *http.Request, via []*http.Request) error {
 	//	return http.ErrUseLastResponse
@@ -33,203 +111,1121 @@ var defaultClient = &http.Client{
 	Timeout: 60 * time.Second,
 }
 
+// verboseTransport logs the request url, response status and round-trip duration.
+type verboseTransport struct {
+	http.RoundTripper
+}
+
+func (t *verboseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	start := time.Now()
+
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if logRequest(req, resp, err, time.Since(start)); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
 func init() {
-	flag.StringVar(&bandName, "band", "", "band name (for convenience)")
-	flag.BoolVar(&tags, "tags", false, "read artists tags")
-	flag.BoolVar(&similarArtists, "similar-artists", false, "read similar artists")
-	flag.BoolVar(&wiki, "wiki", false, "read wiki")
+	flag.StringVar(&bandName, "band", "", "band name or last.fm url (for convenience)")
+	flag.StringVar(&mbid, "mbid", "", "the MusicBrainz id to read the band by")
+	flag.BoolVar(&all, "all", false, "read all sections (explicit section flags take precedence, e.g. -all -wiki=false)")
+	for _, s := range allSections() {
+		sectionFlags[s.name] = flag.Bool(s.name, false, s.usage)
+	}
+	flag.IntVar(&tagsPages, "tags-pages", 1, "number of pages for tags")
 	flag.StringVar(&refFormat, "wiki-ref-format", `%q`, "the reference format for the wiki references in text")
-	flag.BoolVar(&events, "events", false, "read events")
+	flag.BoolVar(&eventsUpcoming, "events-upcoming", false, "read upcoming events only")
+	flag.BoolVar(&eventsPast, "events-past", false, "read past events only")
+	flag.Func("events-since", "read events since the date (YYYY-MM-DD)", dateFlag(&eventsSince))
+	flag.Func("events-until", "read events until the date inclusive (YYYY-MM-DD)", dateFlag(&eventsUntil))
+	flag.IntVar(&albumsPages, "albums-pages", 1, "number of pages for top albums")
+	flag.IntVar(&tracksPages, "tracks-pages", 1, "number of pages for top tracks")
+	flag.IntVar(&maxTracks, "max-tracks", 0, "the maximum number of top tracks (no limit if zero)")
+	flag.BoolVar(&similarMatch, "similar-match", false, "include the similar artists match percent, listeners and thumbnail image")
+	flag.BoolVar(&links, "links", false, "include the external buy and stream links of the top albums and tracks")
+	flag.BoolVar(&overviewSimilar, "overview-similar", false, fmt.Sprintf("add the top %d similar artists from the overview page, without reading the similar artists pages", overviewSimilarMax))
+	flag.Float64Var(&similarMinMatch, "similar-min-match", 0, "drop the similar artists with the match below the fraction, e.g. 0.8, and with unknown match (no filter if zero)")
+	flag.BoolVar(&wikiCompact, "wiki-compact", false, "add the wiki bio as one line with the collapsed whitespace (bio_compact)")
+	flag.Func("albums-sort", "sort the top albums: none or year, the albums without the year go last (default none)", choiceFlag(&albumsSort, "none", "year"))
+	flag.Func("sort", "sort the tags and similar artists: none, name, count or match (default none)", choiceFlag(&sortOrder, "none", "name", "count", "match"))
 	flag.IntVar(&pageNum, "similar-artists-pages", 5, "number of pages for similar artists")
 	flag.IntVar(&pageOffset, "similar-artists-pages-offset", 0, "page offset for similar artists")
-	flag.IntVar(&workersNum, "workers", 1, "the number of workers")
+	flag.IntVar(&pagesConcurrent, "pages-concurrent", 0, "the number of workers for the tags, albums and tracks pages (serial if zero)")
+	flag.IntVar(&workersNum, "workers", 1, "the number of workers for concurrent sections and pages, at least 1")
+	flag.DurationVar(&timeout, "timeout", 0, "the timeout for the whole run or for each request in server mode (no timeout if zero)")
+	flag.DurationVar(&sectionTimeouts.wiki, "wiki-timeout", 0, "the timeout for the wiki section (no timeout if zero)")
+	flag.DurationVar(&sectionTimeouts.tags, "tags-timeout", 0, "the timeout for the tags section (no timeout if zero)")
+	flag.DurationVar(&sectionTimeouts.similarArtists, "similar-timeout", 0, "the timeout for the similar artists section (no timeout if zero)")
+	flag.DurationVar(&pageTimeout, "page-timeout", 15*time.Second, "the timeout for each similar artists page, the timed out page is read once more (no timeout if zero)")
+	flag.DurationVar(&sectionTimeouts.events, "events-timeout", 0, "the timeout for the events section (no timeout if zero)")
+	flag.DurationVar(&sectionTimeouts.albums, "albums-timeout", 0, "the timeout for the top albums section (no timeout if zero)")
+	flag.DurationVar(&sectionTimeouts.tracks, "tracks-timeout", 0, "the timeout for the top tracks section (no timeout if zero)")
+	flag.IntVar(&maxConns, "max-conns", 0, "the maximum number of simultaneous requests to last.fm (the number of workers times -pages-concurrent and -batch-workers if zero)")
+	flag.IntVar(&transportCfg.MaxIdleConnsPerHost, "max-idle-conns", 0, "the number of idle connections kept to last.fm (same as -max-conns default if zero)")
+	flag.BoolVar(&transportCfg.ForceAttemptHTTP2, "http2", true, "attempt HTTP/2 connections")
+	flag.DurationVar(&transportCfg.IdleConnTimeout, "keep-alive", 90*time.Second, "the keep-alive timeout for the idle connections")
+	flag.Int64Var(&maxBodyBytes, "max-body-bytes", DefaultMaxBodyBytes, "fail the pages larger than the size in bytes (no limit if zero)")
+	flag.BoolVar(&includeSources, "include-sources", false, "include the fetched page urls of each section in the _sources field")
+	flag.BoolVar(&bestEffort, "best-effort", false, "output the sections that succeeded and report the failed ones in the _warnings field")
+	flag.IntVar(&retries, "retries", 0, "the number of retries for the network errors, 429 and 5xx responses")
+	flag.IntVar(&retryBudget, "retry-budget", 0, "the total number of retries for all the requests in a run (no limit if zero)")
+	flag.IntVar(&sectionRetries, "section-retries", 0, fmt.Sprintf("the number of the section re-reads on the empty result or the error, after a short delay (at most %d)", maxSectionRetries))
+	flag.BoolVar(&retryEmpty, "retry-on-empty", false, "re-read the empty tags, similar artists and events sections once after a short delay")
+	flag.BoolVar(&verbose, "verbose", false, "log requests to stderr")
+	flag.BoolVar(&logJSON, "log-json", false, "write the logs to stderr as JSON lines with the url, status, duration_ms, band and section fields")
+	flag.BoolVar(&showStats, "stats", false, "print the run summary to stderr")
+	flag.BoolVar(&showVersion, "version", false, "print the version, commit, build date and go version and exit")
+	flag.StringVar(&dumpHTML, "dump-html", "", "the directory to write the raw fetched pages into for debugging")
+	flag.StringVar(&cacheDir, "cache-dir", "", "the directory to cache the fetched pages in, so the repeated runs don't re-fetch them")
+	flag.DurationVar(&cacheTTL, "cache-ttl", 24*time.Hour, "the cached pages expiry (no expiry if zero)")
+	flag.DurationVar(&cacheNegativeTTL, "cache-negative-ttl", time.Hour, "the cached missing (404) bands and pages expiry (no expiry if zero)")
+	flag.BoolVar(&refresh, "refresh", false, "re-fetch the cached pages and update the cache")
+	flag.BoolVar(&offline, "offline", false, "read the pages from the -cache-dir only regardless of the expiry, the missing pages fail the band")
+	flag.BoolVar(&dryRun, "dry-run", false, "print the urls that would be fetched for the band or the -batch bands and exit")
+	flag.BoolVar(&search, "search", false, "print the artist names found by the band name and exit")
+	flag.BoolVar(&random, "random", false, "read the random artist from the music charts page, e.g. for the demos and smoke tests")
+	flag.BoolVar(&resolve, "resolve", false, "resolve the band name to the top search result before reading")
+	flag.StringVar(&batch, "batch", "", "read the band names from the file, one per line (- for stdin), and write one record per line")
+	flag.IntVar(&batchWorkers, "batch-workers", 1, "the number of bands read concurrently in batch mode")
+	flag.IntVar(&minListeners, "min-listeners", 0, "skip the bands with fewer listeners in batch mode (no filter if zero)")
+	flag.BoolVar(&ordered, "ordered", false, "write the batch records in the input order instead of as completed")
+	flag.BoolVar(&batchArray, "batch-array", false, "write the batch records as one json array instead of one record per line")
+	flag.StringVar(&outputDir, "output-dir", "", "write each batch band record into its own file in the directory instead of stdout")
+	flag.BoolVar(&noOverwrite, "no-overwrite", false, "keep the existing -output-dir files")
+	flag.BoolVar(&pretty, "pretty", false, "indent the json output")
+	flag.StringVar(&fromNDJSONPath, "from-ndjson", "", "re-render the band records saved in the json format from the file (- for stdin) without fetching")
+	flag.StringVar(&sqlitePath, "sqlite", "", "save the bands into the sqlite database instead of writing them to stdout")
+	flag.StringVar(&serveAddr, "serve", "", "serve band information over http on the address, e.g. :8080")
+	flag.StringVar(&userAgentFile, "user-agent-file", "", "the file with the user agents, one per line, used in turn for the requests")
+	flag.StringVar(&proxyFile, "proxy-file", "", "the file with the proxy urls, one per line, used in turn for the requests, each with the -max-conns limit")
+	flag.Func("cookie", "the name=value cookie to send with every request, can be repeated", cookieFlag(&cookies))
+	flag.Func("header", "the \"Key: Value\" header to send with every request, can be repeated, replaces the -user-agent-file and -cookie headers with the same key", headerFlag(&headers))
+	flag.BoolVar(&bare, "bare", false, "write only the section data, e.g. the similar artists array, if only one section is read")
+	flag.BoolVar(&includeEmpty, "include-empty", false, "keep the empty fields in the json output, so every record has the same keys")
+	flag.Func("fields", "the comma-separated list of output fields, e.g. band_name,listeners,tags", fieldsFlag(&fields))
+	flag.Func("format", "the output format: json, text or template (default json)", choiceFlag(&format, "json", "text", "template"))
+	flag.StringVar(&templateFile, "template-file", "", "the text/template file for the template format, the band is the template data")
+	flag.Func("color", "colorize the text output: auto, always or never (default auto)", choiceFlag(&color, "auto", "always", "never"))
 
 	flag.Usage = func() {
 		fmt.Fprintln(flag.CommandLine.Output(), "lastfmq - read last.fm band information")
 		fmt.Fprintln(flag.CommandLine.Output(), "usage: lastfmq [flags] <band_name>")
+		fmt.Fprintln(flag.CommandLine.Output(), "       lastfmq [flags] -batch <file>")
+		fmt.Fprintln(flag.CommandLine.Output(), "       lastfmq [flags] -from-ndjson <file>")
+		fmt.Fprintln(flag.CommandLine.Output(), "       lastfmq [flags] -serve <addr>")
 		flag.PrintDefaults()
+		fmt.Fprintln(flag.CommandLine.Output(), "exit codes:")
+		fmt.Fprintln(flag.CommandLine.Output(), "  1	generic failure")
+		fmt.Fprintln(flag.CommandLine.Output(), "  2	band not found")
+		fmt.Fprintln(flag.CommandLine.Output(), "  3	network error or timeout")
+		fmt.Fprintln(flag.CommandLine.Output(), "  4	rate limited by last.fm")
+		fmt.Fprintln(flag.CommandLine.Output(), "  130	interrupted")
 	}
+}
+
+// parseFlags function parses the command-line flags and sets up the default client.
+func parseFlags() {
 
 	flag.Parse()
 
+	setupLogs()
+
+	// the connections limit is derived from the workers, so zero workers would block
+	// every request.
+	workersNum = max(1, workersNum)
+
+	if showVersion {
+		fmt.Println(versionString())
+		os.Exit(0)
+	}
+
 	if bandName == "" {
 		bandName = strings.Join(flag.Args(), " ")
 	}
+
+	if all {
+
+		explicit := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+		for name, section := range sectionFlags {
+			if !explicit[name] {
+				*section = true
+			}
+		}
+	}
+
+	if format == "template" {
+		if templateFile == "" {
+			exit(fmt.Errorf("-template-file is required for the template format"))
+		}
+		var err error
+		if outputTemplate, err = parseTemplate(templateFile); err != nil {
+			exit(err)
+		}
+	}
+
+	if sectionRetries < 0 || sectionRetries > maxSectionRetries {
+		exit(fmt.Errorf("-section-retries must be between 0 and %d", maxSectionRetries))
+	}
+
+	if similarMinMatch < 0 || similarMinMatch > 1 {
+		exit(fmt.Errorf("-similar-min-match must be between 0 and 1"))
+	}
+
+	if offline && (cacheDir == "" || refresh) {
+		exit(fmt.Errorf("-offline requires -cache-dir and can't be used with -refresh"))
+	}
+
+	if bare && len(flagSections()) != 1 {
+		exit(fmt.Errorf("-bare requires exactly one section"))
+	}
+
+	if random && (bandName != "" || mbid != "" || batch != "" || search || dryRun) {
+		exit(fmt.Errorf("-random can't be used with the band name, -mbid, -batch, -search or -dry-run"))
+	}
+
+	if outputDir != "" {
+		if batchArray {
+			exit(fmt.Errorf("-output-dir can't be used with -batch-array"))
+		}
+		if err := os.MkdirAll(outputDir, 0o755); err != nil {
+			exit(err)
+		}
+	}
+
+	if batchArray && format != "" && format != "json" {
+		exit(fmt.Errorf("-batch-array requires the json format"))
+	}
+
+	if userAgentFile != "" {
+		var err error
+		if userAgents, err = readUserAgents(userAgentFile); err != nil {
+			exit(err)
+		}
+	}
+
+	if transportCfg.MaxIdleConnsPerHost <= 0 {
+		transportCfg.MaxIdleConnsPerHost = workersNum * max(1, pagesConcurrent) * max(1, batchWorkers)
+	}
+
+	if maxConns <= 0 {
+		maxConns = workersNum * max(1, pagesConcurrent) * max(1, batchWorkers)
+	}
+
+	if proxyFile != "" {
+		proxies, err := readProxies(proxyFile)
+		if err != nil {
+			exit(err)
+		}
+		defaultClient.Transport = newProxyTransport(transportCfg, proxies, maxConns)
+	} else {
+		defaultClient.Transport = newLimitTransport(newTransport(transportCfg), maxConns)
+	}
+
+	if serveAddr != "" {
+		defaultClient.Transport = &metricsTransport{defaultClient.Transport}
+	}
+
+	if retries > 0 {
+		stats.budget = retryBudget
+		defaultClient.Transport = newRetryTransport(defaultClient.Transport, retries, retryBudget)
+	}
+
+	if verbose {
+		defaultClient.Transport = &verboseTransport{defaultClient.Transport}
+	}
+
+	if showStats {
+		defaultClient.Transport = &statsTransport{defaultClient.Transport}
+	}
+
+	// the cached pages are not counted as the requests.
+	if cacheDir != "" {
+		if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+			exit(err)
+		}
+		defaultClient.Transport = newCacheTransport(defaultClient.Transport, cacheDir, cacheTTL, cacheNegativeTTL, refresh, offline, maxBodyBytes)
+	}
+
+	if dumpHTML != "" {
+		if err := os.MkdirAll(dumpHTML, 0o755); err != nil {
+			exit(err)
+		}
+		defaultClient.Transport = &dumpTransport{RoundTripper: defaultClient.Transport, dir: dumpHTML, maxBodyBytes: maxBodyBytes}
+	}
+}
+
+// dateFlag function returns the flag parser for the YYYY-MM-DD date.
+func dateFlag(t *time.Time) func(string) error {
+	return func(s string) (err error) {
+		*t, err = time.Parse(time.DateOnly, s)
+		return
+	}
 }
 
 const (
-	tagsURL               = "https://www.last.fm/music/%s/+tags"
-	similarArtistsPageURL = "https://www.last.fm/music/%s/+similar?page=%d"
-	wikiURL               = "https://www.last.fm/music/%s/+wiki"
-	overviewURL           = "https://www.last.fm/music/%s"
-	eventsURL             = "https://www.last.fm/music/%s/+events"
+	tagsPagePath           = "/music/%s/+tags?page=%d"
+	similarArtistsPagePath = "/music/%s/+similar?page=%d"
+	wikiPath               = "/music/%s/+wiki"
+	overviewPath           = "/music/%s"
+	eventsPath             = "/music/%s/+events"
+	albumsPagePath         = "/music/%s/+albums?page=%d"
+	tracksPagePath         = "/music/%s/+tracks?page=%d"
+	searchPath             = "/search/artists?q=%s"
+	mbidPath               = "/mbid/%s"
+	chartsPath             = "/charts"
 )
 
 type bandDesc struct {
-	BandName       string   `json:"band_name,omitempty"`
-	Scrobbles      int      `json:"scrobbles,omitempty"`
-	Listeners      int      `json:"listeners,omitempty"`
-	YearsActive    string   `json:"years_active,omitempty"`
-	FoundedIn      string   `json:"founded_in,omitempty"`
-	Born           string   `json:"born,omitempty"`
-	BornIn         string   `json:"born_in,omitempty"`
-	Wiki           *Wiki    `json:"wiki,omitempty"`
-	Tags           []string `json:"tags,omitempty"`
-	SimilarArtists []string `json:"similar_artists,omitempty"`
-	Years          []string `json:"events_years,omitempty"`
+	BandName         string           `json:"band_name,omitempty"`
+	CanonicalName    string           `json:"canonical_name,omitempty"`
+	Scrobbles        int              `json:"scrobbles,omitempty"`
+	Listeners        int              `json:"listeners,omitempty"`
+	MonthlyListeners int64            `json:"monthly_listeners,omitempty"`
+	ListenersTrend   string           `json:"listeners_trend,omitempty"`
+	ListenersDelta   int              `json:"listeners_delta,omitempty"`
+	ReleaseCount     int              `json:"release_count,omitempty"`
+	OnTour           bool             `json:"on_tour,omitempty"`
+	YearsActive      string           `json:"years_active,omitempty"`
+	FoundedIn        string           `json:"founded_in,omitempty"`
+	Born             string           `json:"born,omitempty"`
+	BornIn           string           `json:"born_in,omitempty"`
+	FoundedCity      string           `json:"founded_city,omitempty"`
+	FoundedCountry   string           `json:"founded_country,omitempty"`
+	BornCity         string           `json:"born_city,omitempty"`
+	BornCountry      string           `json:"born_country,omitempty"`
+	ImageURL         string           `json:"image_url,omitempty"`
+	Summary          string           `json:"summary,omitempty"`
+	Wiki             *Wiki            `json:"wiki,omitempty"`
+	Genre            string           `json:"genre,omitempty"`
+	TopTags          []string         `json:"top_tags,omitempty"`
+	Tags             []string         `json:"tags,omitempty"`
+	RelatedTags      []string         `json:"related_tags,omitempty"`
+	SimilarArtists   []string         `json:"similar_artists,omitempty"`
+	SimilarMatch     []*SimilarArtist `json:"similar_artists_match,omitempty"`
+	SimilarTotal     int              `json:"similar_artists_total,omitempty"`
+	Years            []string         `json:"events_years,omitempty"`
+	Events           []*Event         `json:"events,omitempty"`
+	TopAlbums        []*Album         `json:"top_albums,omitempty"`
+	TopTracks        []*Track         `json:"top_tracks,omitempty"`
+	FeaturedTracks   []*Track         `json:"featured_tracks,omitempty"`
+	Warnings         []string         `json:"_warnings,omitempty"`
+	Sources          bandSources      `json:"_sources,omitempty"`
+}
+
+// SimilarArtist is the similar artists page card, the match is the similarity percent.
+type SimilarArtist struct {
+	Name      string `json:"name"`
+	Match     int    `json:"match,omitempty"`
+	Listeners int    `json:"listeners,omitempty"`
+	Image     string `json:"image,omitempty"`
+}
+
+// similarNames function returns the similar artists names.
+func similarNames(similar []*SimilarArtist) []string {
+
+	ret := make([]string, 0, len(similar))
+
+	for _, artist := range similar {
+		ret = append(ret, artist.Name)
+	}
+
+	return ret
 }
 
 func main() {
 
-	if bandName == "" {
+	parseFlags()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg := flagConfig()
+
+	if serveAddr != "" {
+		if err := serve(ctx, NewClient(WithConfig(cfg)), serveAddr); err != nil {
+			exit(err)
+		}
+		return
+	}
+
+	if bandName == "" && mbid == "" && !random && batch == "" && fromNDJSONPath == "" {
 		fmt.Fprintln(os.Stderr, "band name is required")
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitFailure)
+	}
+
+	if dryRun {
+		if err := NewClient(WithConfig(cfg)).runDryRun(ctx, bandName, batch, flagSections()); err != nil {
+			exit(err)
+		}
+		return
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if sqlitePath != "" {
+
+		var err error
+		if cfg.SQLite, err = openSQLite(ctx, sqlitePath); err != nil {
+			exit(err)
+		}
+
+		defer cfg.SQLite.Close()
+	}
+
+	c := NewClient(WithConfig(cfg))
+
+	if fromNDJSONPath != "" {
+
+		f, err := openBatch(fromNDJSONPath)
+		if err != nil {
+			exit(err)
+		}
+
+		defer f.Close()
+
+		if err = c.fromNDJSON(ctx, f, os.Stdout, flagSections()); err != nil {
+			exit(err)
+		}
+
+		return
+	}
+
+	if batch != "" {
+
+		f, err := openBatch(batch)
+		if err != nil {
+			exit(err)
+		}
+
+		names, err := readBatch(f)
+		if f.Close(); err != nil {
+			exit(err)
+		}
+
+		// the failed bands are already counted by the batch.
+		if err = c.runBatch(ctx, names, os.Stdout, flagSections()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			printStats()
+			os.Exit(exitCode(err))
+		}
+
+		printStats()
+		return
 	}
 
+	if search {
+
+		names, err := c.readSearch(ctx, bandName)
+		if err != nil {
+			exit(err)
+		}
+
+		if err = encodeJSON(os.Stdout, names, pretty); err != nil {
+			exit(err)
+		}
+
+		return
+	}
+
+	var err error
+
+	// the mbid is resolved to the band slug, so doesn't need the search.
+	switch {
+	case random:
+		bandName, err = c.readRandomArtist(ctx)
+	case mbid != "":
+		bandName, err = c.resolveMBID(ctx, mbid)
+	default:
+		bandName, err = c.resolveBand(ctx, bandName)
+	}
+
+	if err != nil {
+		exit(err)
+	}
+
+	with := flagSections()
+
+	bandDesc, err := c.readBand(ctx, bandName, with)
+	if err != nil {
+		exit(err)
+	}
+
+	if cfg.SQLite != nil {
+		if err = cfg.SQLite.save(ctx, bandDesc); err != nil {
+			exit(err)
+		}
+		printStats()
+		return
+	}
+
+	out, err := c.renderBand(bandDesc, with)
+	if err != nil {
+		exit(err)
+	}
+
+	if _, err = os.Stdout.Write(out); err != nil {
+		exit(err)
+	}
+
+	printStats()
+
+}
+
+// resolveBand function resolves the band name to the top search result if -resolve is set.
+// The last.fm url is resolved to the band slug as is, without search.
+func (c *Client) resolveBand(ctx context.Context, bandName string) (string, error) {
+
+	if strings.HasPrefix(bandName, "https://") || strings.HasPrefix(bandName, "http://") {
+		u, err := url.Parse(bandName)
+		if err != nil {
+			return "", fmt.Errorf("resolve: %w", err)
+		}
+		if host := strings.ToLower(u.Hostname()); host != "last.fm" && !strings.HasSuffix(host, ".last.fm") {
+			return "", fmt.Errorf("resolve: not a last.fm url: %s", u)
+		}
+		return bandSlug(u)
+	}
+
+	if !c.cfg.Resolve {
+		return bandName, nil
+	}
+
+	names, err := c.readSearch(ctx, bandName)
+	if err != nil {
+		return "", err
+	}
+
+	if len(names) == 0 {
+		return "", fmt.Errorf("resolve: %w: %s", ErrBandNotFound, bandName)
+	}
+
+	if c.cfg.Verbose {
+		log.Printf("resolve: using %q for %q", names[0], bandName)
+	}
+
+	return names[0], nil
+}
+
+// resolveMBID function resolves the MusicBrainz id to the band slug with the last.fm
+// /mbid redirect.
+func (c *Client) resolveMBID(ctx context.Context, mbid string) (string, error) {
+
+	if !mbidRe.MatchString(mbid) {
+		return "", fmt.Errorf("resolve_mbid: invalid mbid %q", mbid)
+	}
+
+	resp, err := c.fetch(ctx, mbidPath, strings.ToLower(mbid))
+	if err != nil {
+		if statusCode(err) == http.StatusNotFound {
+			return "", fmt.Errorf("resolve_mbid: %w: %s", ErrBandNotFound, mbid)
+		}
+		return "", fmt.Errorf("resolve_mbid: %w", err)
+	}
+
+	resp.Body.Close()
+
+	slug, err := bandSlug(resp.Request.URL)
+	if err != nil {
+		return "", fmt.Errorf("resolve_mbid: %w: %s", ErrBandNotFound, mbid)
+	}
+
+	if c.cfg.Verbose {
+		log.Printf("resolve_mbid: using %q for %s", slug, mbid)
+	}
+
+	return slug, nil
+}
+
+// mbidRe matches the MusicBrainz id.
+var mbidRe = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// bandSlug function returns the band slug from the /music/<band_name>[/...] url path,
+// the slug is kept escaped as in the url.
+func bandSlug(u *url.URL) (string, error) {
+
+	path, ok := strings.CutPrefix(u.EscapedPath(), "/music/")
+	if !ok {
+		return "", fmt.Errorf("band_slug: not a band url: %s", u)
+	}
+
+	slug, _, _ := strings.Cut(path, "/")
+
+	if name, err := url.PathUnescape(slug); err != nil || strings.TrimSpace(strings.ReplaceAll(name, "+", " ")) == "" {
+		return "", fmt.Errorf("band_slug: invalid band name in url: %s", u)
+	}
+
+	return slug, nil
+}
+
+// renderBand function renders the band description in the configured format with the
+// configured fields, the bare output is the data of the only enabled section.
+func (c *Client) renderBand(desc *bandDesc, with sections) ([]byte, error) {
+
+	var b bytes.Buffer
+
+	switch c.cfg.Format {
+	case "text":
+		if err := writeText(&b, desc, c.cfg.Color, c.cfg.Fields); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	case "template":
+		if err := writeTemplate(&b, c.cfg.Template, desc); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+
+	var out any = desc
+
+	if c.cfg.Bare {
+		out = bareSection(desc, with)
+	}
+
+	if c.cfg.IncludeEmpty && !c.cfg.Bare {
+		out = withEmptyFields(desc)
+	}
+
+	if len(c.cfg.Fields) > 0 && !c.cfg.Bare {
+		var err error
+		if out, err = selectFields(out, c.cfg.Fields); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := encodeJSON(&b, out, c.cfg.Pretty); err != nil {
+		return nil, fmt.Errorf("render_band: encode: %w", err)
+	}
+
+	return b.Bytes(), nil
+}
+
+// encodeJSON function writes the value as json, indented if pretty is set.
+func encodeJSON(w io.Writer, v any, pretty bool) error {
+
+	enc := json.NewEncoder(w)
+
+	if pretty {
+		enc.SetIndent("", "  ")
+	}
+
+	return enc.Encode(v)
+}
+
+// flagConfig function returns the client settings from the command-line flags.
+func flagConfig() Config {
+	return Config{
+		RefFormat:             refFormat,
+		Workers:               workersNum,
+		SimilarArtistsPages:   pageNum,
+		SimilarArtistsOffset:  pageOffset,
+		TagsPages:             tagsPages,
+		AlbumsPages:           albumsPages,
+		TracksPages:           tracksPages,
+		MaxTracks:             maxTracks,
+		AlbumsSort:            albumsSort,
+		EventsUpcoming:        eventsUpcoming,
+		EventsPast:            eventsPast,
+		EventsSince:           eventsSince,
+		EventsUntil:           eventsUntil,
+		WikiTimeout:           sectionTimeouts.wiki,
+		TagsTimeout:           sectionTimeouts.tags,
+		SimilarArtistsTimeout: sectionTimeouts.similarArtists,
+		PageTimeout:           pageTimeout,
+		MaxBodyBytes:          maxBodyBytes,
+		EventsTimeout:         sectionTimeouts.events,
+		AlbumsTimeout:         sectionTimeouts.albums,
+		TracksTimeout:         sectionTimeouts.tracks,
+		RetryOnEmpty:          retryEmpty,
+		SectionRetries:        sectionRetries,
+		SimilarMatch:          similarMatch,
+		SimilarMinMatch:       similarMinMatch,
+		OverviewSimilar:       overviewSimilar,
+		Links:                 links,
+		Sort:                  sortOrder,
+		WikiCompact:           wikiCompact,
+		BestEffort:            bestEffort,
+		Cookies:               cookies,
+		Headers:               headers,
+		UserAgents:            userAgents,
+		PagesConcurrent:       pagesConcurrent,
+		IncludeSources:        includeSources,
+		Verbose:               verbose,
+		Resolve:               resolve,
+		Search:                search,
+		MBID:                  mbid,
+		BatchWorkers:          batchWorkers,
+		Ordered:               ordered,
+		BatchArray:            batchArray,
+		OutputDir:             outputDir,
+		NoOverwrite:           noOverwrite,
+		MinListeners:          minListeners,
+		Format:                format,
+		Template:              outputTemplate,
+		Fields:                fields,
+		Bare:                  bare,
+		IncludeEmpty:          includeEmpty,
+		Pretty:                pretty,
+		Color:                 useColor(),
+	}
+}
+
+// flagSections function returns the sections enabled with the command-line flags.
+func flagSections() sections {
+
+	with := make(sections)
+
+	for name, on := range sectionFlags {
+		if *on {
+			with[name] = true
+		}
+	}
+
+	return with
+}
+
+// readBand function reads the band overview and the enabled sections.
+func (c *Client) readBand(ctx context.Context, bandName string, with sections) (*bandDesc, error) {
+
 	var (
 		err      error
 		bandDesc *bandDesc
+		sources  *sourceSet
 	)
 
-	if bandDesc, err = readOverview(context.TODO(), bandName); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	stats.bands.Add(1)
+
+	if bandName, err = c.resolveSlug(ctx, bandName); err != nil {
+		return nil, err
 	}
 
-	if wiki {
-		if bandDesc.Wiki, err = readWiki(context.TODO(), bandName); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+	if c.cfg.IncludeSources {
+		sources = &sourceSet{}
+	}
+
+	// overview goes first to validate the band.
+	if err = sourced(sources, "overview", logged(bandName, "overview", func(ctx context.Context) (err error) {
+		bandDesc, err = c.readOverview(ctx, bandName)
+		return
+	}))(ctx); err != nil {
+		return nil, err
+	}
+
+	var (
+		sections []func(context.Context) error
+		mu       sync.Mutex
+		results  = make(map[string]any)
+	)
+
+	for _, e := range extractors {
+
+		if !e.Enabled(with) {
+			continue
 		}
+
+		sections = append(sections, sourced(sources, sourceName(e), logged(bandName, e.Name(), func(ctx context.Context) error {
+
+			v, err := c.retrySection(ctx, e.Name(), func(ctx context.Context) (any, error) {
+				return e.Fetch(ctx, c, bandName)
+			})
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			results[e.Name()] = v
+			return nil
+		})))
 	}
 
-	if tags {
-		if bandDesc.Tags, bandDesc.SimilarArtists, err = readTags(bandName); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+	if c.cfg.BestEffort {
+		sections = c.bestEffort(bandDesc, sections)
+	}
+
+	if err = runTasks(ctx, c.cfg.Workers, sections...); err != nil {
+		return nil, err
+	}
+
+	// sections finish in any order.
+	slices.Sort(bandDesc.Warnings)
+
+	if sources != nil {
+		bandDesc.Sources = sources.urls
+	}
+
+	for _, e := range extractors {
+		if v, ok := results[e.Name()]; ok {
+			e.Set(c, bandDesc, v, with)
 		}
 	}
 
-	if similarArtists {
+	// the sidebar similar artists have no counts, so are sorted by name only.
+	if c.cfg.Sort == "name" {
+		sortNames(bandDesc.Tags)
+		sortNames(bandDesc.SimilarArtists)
+	}
+
+	return bandDesc, nil
+}
+
+// compareNames function compares the names case-insensitively.
+func compareNames(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// sortNames function sorts the names case-insensitively.
+func sortNames(names []string) {
+	slices.SortStableFunc(names, compareNames)
+}
+
+// sortSimilar function sorts the similar artists by the -sort order: by name, by the
+// listeners count or by the match percent, the largest first. The page order is kept
+// for the equal artists and for the none order.
+func sortSimilar(similar []*SimilarArtist, order string) {
+
+	var cmp func(a, b *SimilarArtist) int
+
+	switch order {
+	case "name":
+		cmp = func(a, b *SimilarArtist) int { return compareNames(a.Name, b.Name) }
+	case "count":
+		cmp = func(a, b *SimilarArtist) int { return b.Listeners - a.Listeners }
+	case "match":
+		cmp = func(a, b *SimilarArtist) int { return b.Match - a.Match }
+	default:
+		return
+	}
 
-		readSimilarArtists := readSimilarArtists
-		if workersNum > 1 {
-			readSimilarArtists = readSimilarArtistsAsync
+	slices.SortStableFunc(similar, cmp)
+}
+
+// filterSimilar function returns the similar artists with the match percent at least
+// the minMatch fraction, the artists with unknown match are dropped as well. All the
+// artists are returned if minMatch is zero.
+func filterSimilar(similar []*SimilarArtist, minMatch float64) []*SimilarArtist {
+
+	if minMatch <= 0 {
+		return similar
+	}
+
+	return slices.DeleteFunc(similar, func(artist *SimilarArtist) bool {
+		return artist.Match == 0 || float64(artist.Match) < minMatch*100
+	})
+}
+
+// bestEffort function wraps the section tasks so that the failing section is logged and
+// recorded into the band warnings instead of aborting the other sections (-best-effort).
+// The cancellation of the band context is still an error.
+func (c *Client) bestEffort(bandDesc *bandDesc, tasks []func(context.Context) error) []func(context.Context) error {
+
+	mu, ret := new(sync.Mutex), make([]func(context.Context) error, len(tasks))
+
+	for i, task := range tasks {
+		ret[i] = func(ctx context.Context) error {
+
+			err := task(ctx)
+			if err == nil || ctx.Err() != nil {
+				return err
+			}
+
+			logWarning(bandDesc.BandName, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			bandDesc.Warnings = append(bandDesc.Warnings, err.Error())
+			return nil
 		}
+	}
 
-		if bandDesc.SimilarArtists, err = readSimilarArtists(bandName, pageNum, pageOffset); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+	return ret
+}
+
+// retryOnEmptyDelay is the delay before re-reading the empty section.
+var retryOnEmptyDelay = time.Second
+
+// retryOnEmpty function re-reads the section once after a short delay if it has no
+// results and the -retry-on-empty is set, so a partially loaded page is not taken as
+// empty section. The second result is accepted as is.
+func (c *Client) retryOnEmpty(ctx context.Context, section string, read func(context.Context) (int, error)) error {
+
+	n, err := read(ctx)
+	if err != nil || n > 0 || !c.cfg.RetryOnEmpty {
+		return err
+	}
+
+	if c.cfg.Verbose {
+		log.Printf("%s: empty, retrying in %v", section, retryOnEmptyDelay)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(retryOnEmptyDelay):
+	}
+
+	_, err = read(ctx)
+	return err
+}
+
+// maxSectionRetries is the maximum number of the section re-reads.
+const maxSectionRetries = 10
+
+// sectionRetryDelay is the delay before re-reading the failed or empty section.
+var sectionRetryDelay = time.Second
+
+// retrySection function re-reads the whole section up to the -section-retries times
+// after a short delay if it fails or has no results, independently of the request
+// retries. The band not found, the rate limit, the consent and the cache miss errors are
+// not retried, and the last result is accepted as is.
+func (c *Client) retrySection(ctx context.Context, section string, fetch func(context.Context) (any, error)) (any, error) {
+
+	retries := min(c.cfg.SectionRetries, maxSectionRetries)
+
+	for attempt := 1; ; attempt++ {
+
+		v, err := fetch(ctx)
+		if attempt > retries || ctx.Err() != nil || !isSectionRetryable(v, err) {
+			return v, err
+		}
+
+		if c.cfg.Verbose {
+			if err != nil {
+				log.Printf("%s: attempt %d of %d: %v, retrying in %v", section, attempt, retries+1, err, sectionRetryDelay)
+			} else {
+				log.Printf("%s: attempt %d of %d: empty, retrying in %v", section, attempt, retries+1, sectionRetryDelay)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(sectionRetryDelay):
 		}
 	}
+}
 
-	if events {
-		if bandDesc.Years, err = readEventYears(context.TODO(), bandName); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+// isSectionRetryable function reports whether the section result or error is worth
+// re-reading the section.
+func isSectionRetryable(v any, err error) bool {
+
+	if err == nil {
+		return isEmptySection(v)
+	}
+
+	for _, target := range []error{ErrBandNotFound, ErrRateLimited, ErrConsentRequired, ErrCacheMiss} {
+		if errors.Is(err, target) {
+			return false
 		}
 	}
 
-	if err = json.NewEncoder(os.Stdout).Encode(bandDesc); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	// the missing section page is not loaded by the re-read.
+	return statusCode(err) != http.StatusNotFound
+}
+
+// withTimeout function limits the task with the timeout, if set, over the task context.
+func withTimeout(timeout time.Duration, task func(context.Context) error) func(context.Context) error {
+
+	if timeout <= 0 {
+		return task
 	}
 
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return task(ctx)
+	}
+}
+
+// runTasks function runs the tasks on the pool of workers and returns the first error occurred.
+func runTasks(ctx context.Context, workers int, tasks ...func(context.Context) error) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	taskC, errC, wg := make(chan func(context.Context) error), make(chan error, len(tasks)), new(sync.WaitGroup)
+
+	for i := 0; i < max(1, min(workers, len(tasks))); i++ {
+
+		wg.Add(1)
+
+		go func() {
+
+			defer wg.Done()
+
+			for task := range taskC {
+				if err := task(ctx); err != nil {
+					errC <- err
+					cancel() // stop other tasks.
+				}
+			}
+		}()
+	}
+
+	for _, task := range tasks {
+		taskC <- task
+	}
+
+	close(taskC)
+	wg.Wait()
+	close(errC)
+
+	return <-errC
 }
 
 const (
 	pageSize = 10
 )
 
-func readSimilarArtistsAsync(bandName string, pages, offset int) ([]string, error) {
+// overviewSimilarMax is the maximum number of the overview similar artists.
+const overviewSimilarMax = 8
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// readSimilarArtistsAsync function reads the first page, which has the total number of
+// the similar artists, and the rest of the pages up to the last one with the workers.
+// All the pages are read if the first page has no total.
+func (c *Client) readSimilarArtistsAsync(ctx context.Context, bandName string, pages, offset int) ([]*SimilarArtist, int, error) {
 
-	type outValue struct {
-		page    int
-		artists []string
+	if pages <= 0 {
+		return []*SimilarArtist{}, 0, nil
 	}
 
-	pageCount, outC, errC, wg := new(atomic.Int32), make(chan outValue), make(chan error, 1), new(sync.WaitGroup)
-	defer close(outC)
+	first, total, err := c.readSimilarArtistsPageTimeout(ctx, bandName, 1+offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read_similar_artists: %w", err)
+	}
 
-	for i := 0; i < workersNum; i++ {
+	similar, err := fetchPagesConcurrent(ctx, func(ctx context.Context, pageNum int) ([]*SimilarArtist, error) {
+		similar, _, err := c.readSimilarArtistsPageTimeout(ctx, bandName, pageNum)
+		return similar, err
+	}, max(0, similarPages(pages, offset, total)-1), offset+1, c.cfg.Workers, c.cfg.Verbose)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read_similar_artists: %w", err)
+	}
 
-		wg.Add(1)
+	return append(first, similar...), total, nil
+}
 
-		go func(ctx context.Context) {
+// readSimilarArtists function reads the similar artists pages one by one, up to the last
+// page by the total number of the similar artists if known, and returns the artists and
+// the total.
+func (c *Client) readSimilarArtists(ctx context.Context, bandName string, pages, offset int) ([]*SimilarArtist, int, error) {
 
-			defer wg.Done()
+	ret := []*SimilarArtist{}
 
-			for pageNum := int(pageCount.Add(1)); pageNum <= pages+offset; pageNum = int(pageCount.Add(1)) {
+	total, err := c.eachSimilarArtist(ctx, bandName, pages, offset, func(artist *SimilarArtist) error {
+		ret = append(ret, artist)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("read_similar_artists: %w", err)
+	}
 
-				similar, err := readSimilarArtistsPage(ctx, bandName, pageNum)
-				if err != nil {
-					errC <- err
-					continue
-				}
+	return ret, total, nil
+}
 
-				if len(similar) == 0 {
-					return
-				}
+// SimilarArtistsFunc function reads the similar artists pages one by one, same as the
+// similar artists section, and calls fn for each artist name as the page is parsed, so
+// the artists are not kept in memory. The read stops at the first fn error, which is
+// returned.
+func (c *Client) SimilarArtistsFunc(ctx context.Context, bandName string, pages, offset int, fn func(artist string) error) error {
 
-				outC <- outValue{pageNum, similar}
-			}
+	bandName, err := c.resolveSlug(ctx, bandName)
+	if err != nil {
+		return fmt.Errorf("similar_artists_func: %w", err)
+	}
 
-		}(ctx)
+	if _, err = c.eachSimilarArtist(ctx, bandName, pages, offset, func(artist *SimilarArtist) error {
+		return fn(artist.Name)
+	}); err != nil {
+		return fmt.Errorf("similar_artists_func: %w", err)
 	}
 
-	doneC := make(chan struct{})
+	return nil
+}
 
-	go func() {
-		wg.Wait()
-		doneC <- struct{}{}
-	}()
+// eachSimilarArtist function reads the similar artists pages one by one, up to the last
+// page by the total number of the similar artists if known, calls fn for each artist,
+// and returns the total.
+func (c *Client) eachSimilarArtist(ctx context.Context, bandName string, pages, offset int, fn func(*SimilarArtist) error) (int, error) {
 
-	var errs []error
+	var total int
 
-	var ret = make([]string, pageSize*(pages))
-	var retSize int
+	for i := 1 + offset; i <= similarPages(pages, offset, total)+offset; i++ {
 
-loop:
-	for {
-		select {
-		case <-doneC:
-			break loop // all goroutines terminated.
-		case err := <-errC:
-			errs = append(errs, err) // error occurred, wait for other goroutines.
-		case val := <-outC:
-			retSize += len(val.artists)
-			copy(ret[(val.page-1)*pageSize:val.page*pageSize], val.artists)
+		// stop before the next page if canceled, same as the async workers.
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		similar, n, err := c.readSimilarArtistsPageTimeout(ctx, bandName, i)
+		if err != nil {
+			return 0, err
+		}
+
+		if total == 0 {
+			total = n
+		}
+
+		for _, artist := range similar {
+			if err := fn(artist); err != nil {
+				return 0, err
+			}
 		}
 	}
 
-	if len(errs) > 0 {
-		return nil, fmt.Errorf("read_similar_artists: %v", errs[0])
+	return total, nil
+}
+
+// similarPages function returns the number of the similar artists pages to read after
+// the offset, bound by the last page of the total number of artists if known.
+func similarPages(pages, offset, total int) int {
+
+	if total <= 0 {
+		return pages
 	}
 
-	return ret[:retSize], nil
+	return max(0, min(pages, (total+pageSize-1)/pageSize-offset))
 }
 
-func readSimilarArtists(bandName string, pages, offset int) ([]string, error) {
+// readSimilarArtistsPageTimeout function reads the similar artists page within the
+// -page-timeout, so the slow page fails fast instead of holding the section. The timed
+// out page is read once more, unless the section context is done.
+func (c *Client) readSimilarArtistsPageTimeout(ctx context.Context, bandName string, pageNum int) ([]*SimilarArtist, int, error) {
 
-	ret := []string{}
+	if c.cfg.PageTimeout <= 0 {
+		return c.readSimilarArtistsPage(ctx, bandName, pageNum)
+	}
 
-	for i := 1 + offset; i <= pages+offset; i++ {
-		similar, err := readSimilarArtistsPage(context.TODO(), bandName, i)
-		if err != nil {
-			return nil, fmt.Errorf("read_similar_artists: %v", err)
-		}
+	read := func() ([]*SimilarArtist, int, error) {
+		ctx, cancel := context.WithTimeout(ctx, c.cfg.PageTimeout)
+		defer cancel()
+		return c.readSimilarArtistsPage(ctx, bandName, pageNum)
+	}
 
-		ret = append(ret, similar...)
+	similar, total, err := read()
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		if c.cfg.Verbose {
+			log.Printf("read_similar_artists: page %d: timed out, retrying", pageNum)
+		}
+		similar, total, err = read()
 	}
 
-	return ret, nil
+	return similar, total, err
 }
 
-func readOverview(ctx context.Context, bandName string) (*bandDesc, error) {
+func (c *Client) readOverview(ctx context.Context, bandName string) (*bandDesc, error) {
 
 	if bandName == "" {
 		return nil, fmt.Errorf("read_overview: band name is required")
@@ -237,29 +1233,31 @@ func readOverview(ctx context.Context, bandName string) (*bandDesc, error) {
 
 	ret := &bandDesc{}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(overviewURL, bandName), nil)
-	if err != nil {
-		return nil, fmt.Errorf("read_overview: new_request_with_context", err)
-	}
-
-	resp, err := defaultClient.Do(req)
+	resp, err := c.fetch(ctx, overviewPath, bandName)
 	if err != nil {
-		return nil, fmt.Errorf("read_overview: http_get: %v", err)
+		if statusCode(err) == http.StatusNotFound {
+			return nil, fmt.Errorf("read_overview: %w: %s", ErrBandNotFound, bandName)
+		}
+		return nil, fmt.Errorf("read_overview: %w", err)
 	}
 
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusNotFound {
-			return nil, fmt.Errorf("read_overview: band not found: %s", bandName)
+	// check the final url in case of redirect to the canonical band name, the paths are
+	// compared escaped, since the request url is escaped, i.e. Björk is Bj%C3%B6rk.
+	if u, err := url.Parse(c.url(overviewPath, bandName)); err != nil || resp.Request.URL.EscapedPath() != u.EscapedPath() {
+		if ret.CanonicalName = bandNameFromURL(resp.Request.URL); c.cfg.Verbose {
+			log.Printf("read_overview: redirected to %s", resp.Request.URL)
 		}
-		return nil, fmt.Errorf("read_overview: status: %s (%+v)", resp.Status, resp.Header)
 	}
 
 	var (
 		startMetadata bool
+		startFeatured bool
+		startSimilar  bool
 		dt            string
 		intAbbr       string
+		ld            []ldObject
 	)
 
 	tokenizer := html.NewTokenizer(resp.Body)
@@ -269,107 +1267,198 @@ func readOverview(ctx context.Context, bandName string) (*bandDesc, error) {
 		switch tok {
 		case html.EndTagToken:
 			if startMetadata {
-				if containsAttr(tokenizer, TagAttr("dl", "")) != "" {
+				if htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("dl", "")) != "" {
 					startMetadata = false
 				}
 			}
+			if startFeatured {
+				if htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("table", "")) != "" {
+					startFeatured = false
+				}
+			}
+			if startSimilar {
+				if htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("ol", "")) != "" {
+					startSimilar = false
+				}
+			}
 		case html.StartTagToken:
 			if startMetadata {
 
-				switch containsAttr(tokenizer,
-					TagAttr("dt", ""),
-					TagAttr("dd", "")) {
+				switch htmlq.ContainsAttr(tokenizer,
+					htmlq.TagAttr("dt", ""),
+					htmlq.TagAttr("dd", "")) {
 
 				case "dt":
 
-					if tokenizer.Next() != html.TextToken {
-						continue
-					}
-					dt = string(tokenizer.Text())
+					dt = htmlq.ReadText(tokenizer, "dt")
 
 				case "dd":
 
-					if tokenizer.Next() != html.TextToken {
-						continue
-					}
-
-					switch dt {
+					switch dd := htmlq.ReadText(tokenizer, "dd"); dt {
 					case "Years Active":
-						ret.YearsActive = string(tokenizer.Text())
+						ret.YearsActive = dd
 					case "Founded In":
-						ret.FoundedIn = string(tokenizer.Text())
+						ret.FoundedIn = dd
 					case "Born":
-						ret.Born = string(tokenizer.Text())
+						ret.Born = dd
 					case "Born In":
-						ret.BornIn = string(tokenizer.Text())
+						ret.BornIn = dd
+					}
+				}
+			} else if startFeatured {
+
+				// the featured tracks have the top tracks page chartlist layout.
+				switch attr := htmlq.ContainsAttr(tokenizer,
+					htmlq.TagAttr("tr", "class", "chartlist-row"),
+					htmlq.TagAttr("td", "class", "chartlist-name"),
+					htmlq.TagAttr("span", "class", "chartlist-count-bar-value")); attr {
+
+				case "chartlist-row":
+					ret.FeaturedTracks = append(ret.FeaturedTracks, &Track{})
+				case "":
+					// noop.
+				default:
+
+					if len(ret.FeaturedTracks) == 0 {
+						continue
+					}
+
+					track := ret.FeaturedTracks[len(ret.FeaturedTracks)-1]
+
+					switch attr {
+					case "chartlist-name":
+						track.Title = htmlq.ReadText(tokenizer, "td")
+					case "chartlist-count-bar-value":
+						if tokenizer.Next() != html.TextToken {
+							continue
+						}
+						track.Listeners = parseCount(string(tokenizer.Text()))
 					}
 				}
+			} else if startSimilar {
+
+				// the sidebar artists are in the match order, so the first ones are kept.
+				if htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("a", "class", "link-block-target")) == "" || len(ret.SimilarArtists) >= overviewSimilarMax {
+					continue
+				}
+
+				if name := htmlq.ReadText(tokenizer, "a"); name != "" {
+					ret.SimilarArtists = append(ret.SimilarArtists, name)
+				}
 			} else {
-				switch attr := containsAttr(tokenizer,
-					TagAttr("dl", "class", "catalogue-metadata"),
-					TagAttr("h1", "class", "header-new-title"),
-					TagAttr("abbr", "title", "*"),
-					TagAttr("h4", "class", "header-metadata-tnew-title")); attr {
+				switch attr, iter := htmlq.MatchAttr(tokenizer,
+					htmlq.TagAttr("dl", "class", "catalogue-metadata"),
+					htmlq.TagAttr("script", "type", "application/ld+json"),
+					htmlq.TagAttr("table", "class", "chartlist"),
+					htmlq.TagAttr("ol", "class", "artist-similar-artists-sidebar-items"),
+					htmlq.TagAttr("h1", "class", "header-new-title"),
+					htmlq.TagAttr("abbr", "title", "*"),
+					htmlq.TagAttr("abbr", ""),
+					htmlq.TagAttr("h4", "class", "header-metadata-tnew-title"),
+					htmlq.TagAttr("div", "class", "header-new-background-image"),
+					htmlq.TagAttr("img", "class", "header-new-background-image"),
+					htmlq.TagAttr("div", "class", "wiki-block-inner-2"),
+					htmlq.TagAttr("a", "class", "header-new-on-tour"),
+					htmlq.TagAttr("span", "class", "header-new-on-tour", "header-metadata-tnew-trend")); attr {
 				case "catalogue-metadata":
 					startMetadata = true
-				case "header-new-title":
-					if tokenizer.Next() != html.TextToken {
-						continue
+				case "chartlist":
+					startFeatured = true
+				case "artist-similar-artists-sidebar-items":
+					startSimilar = c.cfg.OverviewSimilar
+				case "application/ld+json":
+					if tokenizer.Next() == html.TextToken {
+						ld = append(ld, ldObjects(tokenizer.Text(), "MusicGroup")...)
 					}
-					ret.BandName = string(tokenizer.Text())
+				case "wiki-block-inner-2":
+					ret.Summary = readSummary(tokenizer)
+				case "header-new-on-tour":
+					ret.OnTour = true
+				case "header-metadata-tnew-trend":
+					ret.ListenersTrend, ret.ListenersDelta = readTrend(tokenizer, iter)
+				case "header-new-background-image":
+					if ret.ImageURL == "" {
+						ret.ImageURL = imageURL(iter)
+					}
+				case "header-new-title":
+					ret.BandName = htmlq.ReadText(tokenizer, "h1")
 				case "header-metadata-tnew-title":
 					if tokenizer.Next() != html.TextToken {
 						continue
 					}
-					intAbbr = strings.TrimSpace(string(tokenizer.Text()))
+					intAbbr = htmlq.NormalizeSpace(string(tokenizer.Text()))
 				case "":
 					// noop.
 				default:
-					// abbr title=*
-					switch intAbbr {
+
+					// abbr title=* holds the precise count, like "4,532,198" or "4,532,198 listeners",
+					// the element text is the rounded display, like "4.5M", used if there is no title.
+					count := attr
+					if attr == "abbr" {
+						if tokenizer.Next() != html.TextToken {
+							continue
+						}
+						count = string(tokenizer.Text())
+					}
+
+					switch n := parseAbbr(count); intAbbr {
 					case "Scrobbles":
-						ret.Scrobbles, _ = strconv.Atoi(strings.ReplaceAll(attr, ",", ""))
+						ret.Scrobbles = n
 					case "Listeners":
-						ret.Listeners, _ = strconv.Atoi(strings.ReplaceAll(attr, ",", ""))
+						ret.Listeners = n
+					case "Monthly Listeners":
+						ret.MonthlyListeners = int64(n)
+					case "Releases", "Albums":
+						ret.ReleaseCount = n
 					default:
 					}
 
+					// the heading applies to its own count only.
+					intAbbr = ""
+
 				}
 			}
 		}
 	}
 
-	if err := tokenizer.Err(); err != nil && err != io.EOF {
-		return nil, fmt.Errorf("read_overview: tokenizer: %v", err)
-	}
-
-	return ret, nil
-
-}
-
-func readEventYears(ctx context.Context, bandName string) ([]string, error) {
-
-	if bandName == "" {
-		return nil, fmt.Errorf("read_event_years: band name is required")
+	if err := tokenizer.Err(); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("read_overview: tokenizer: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(eventsURL, bandName), nil)
-	if err != nil {
-		return nil, fmt.Errorf("read_event_years: new_request_with_context", err)
+	// the structured data is more robust than the markup, so takes precedence.
+	if len(ld) > 0 {
+		if name := ld[0].string("name"); name != "" {
+			ret.BandName = name
+		}
+		if image := ld[0].string("image"); image != "" {
+			ret.ImageURL = image
+		}
+		// the tags, if read, take precedence over the structured data genre.
+		ret.Genre = ld[0].string("genre")
 	}
 
-	resp, err := defaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("read_event_years: http_get: %v", err)
+	// last.fm may respond with 200 and the "not found" page, which has no band title.
+	if ret.BandName == "" {
+		return nil, fmt.Errorf("read_overview: %w: %s", ErrBandNotFound, bandName)
 	}
 
-	defer resp.Body.Close()
+	if ret.FoundedIn != "" {
+		ret.FoundedCity, ret.FoundedCountry = splitLocation(ret.FoundedIn)
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("read_event_years: status: %s (%+v)", resp.Status, resp.Header)
+	if ret.BornIn != "" {
+		ret.BornCity, ret.BornCountry = splitLocation(ret.BornIn)
 	}
 
-	tokenizer := html.NewTokenizer(resp.Body)
+	return ret, nil
+
+}
+
+// parseEventYears function parses the event years navigation of the events page, the
+// parse stops with the context error once the context is done.
+func parseEventYears(ctx context.Context, data []byte) ([]string, error) {
+
+	tokenizer := html.NewTokenizer(&contextBody{ReadCloser: io.NopCloser(bytes.NewReader(data)), ctx: ctx})
 
 	var startNav bool
 	var years []string
@@ -380,24 +1469,24 @@ loop:
 		switch tok {
 		case html.EndTagToken:
 			if startNav {
-				if containsAttr(tokenizer, TagAttr("nav", "")) != "" {
+				if htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("nav", "")) != "" {
 					break loop
 				}
 			}
 		case html.StartTagToken:
 			if startNav {
-				if containsAttr(tokenizer, TagAttr("a", "class", "secondary-nav-item-link")) != "" {
+				if htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("a", "class", "secondary-nav-item-link")) != "" {
 					if tokenizer.Next() != html.TextToken {
 						continue
 					}
-					txt := strings.TrimSpace(string(tokenizer.Text()))
+					txt := htmlq.NormalizeSpace(string(tokenizer.Text()))
 					if txt == "" {
 						continue
 					}
 					years = append(years, txt)
 				}
 			} else {
-				if containsAttr(tokenizer, TagAttr("nav", "aria-label", "Event Year Navigation")) != "" {
+				if htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("nav", "aria-label", "Event Year Navigation")) != "" {
 					startNav = true
 				}
 			}
@@ -405,33 +1494,92 @@ loop:
 	}
 
 	if err := tokenizer.Err(); err != nil && err != io.EOF {
-		return nil, fmt.Errorf("read_event_years: tokenizer: %v", err)
+		return nil, err
 	}
 
 	return years, nil
 }
 
 type Event struct {
-	Date    string
-	Address *EventAddress
-	Lineup  string
+	Date     *time.Time    `json:"date,omitempty"`
+	DateRaw  string        `json:"date_raw,omitempty"`
+	Address  *EventAddress `json:"address,omitempty"`
+	Lineup   string        `json:"lineup,omitempty"`
+	Upcoming bool          `json:"upcoming"`
 }
 
 type EventAddress struct {
-	Name       string
-	Street     string
-	Locality   string
-	Code       string
-	Country    string
-	Telephone  string
-	DetailsWeb string
-	MapWeb     string
+	Name       string  `json:"name,omitempty"`
+	Street     string  `json:"street,omitempty"`
+	Locality   string  `json:"locality,omitempty"`
+	Code       string  `json:"code,omitempty"`
+	Country    string  `json:"country,omitempty"`
+	Telephone  string  `json:"telephone,omitempty"`
+	DetailsWeb string  `json:"details_web,omitempty"`
+	MapWeb     string  `json:"map_web,omitempty"`
+	Lat        float64 `json:"lat,omitempty"`
+	Lon        float64 `json:"lon,omitempty"`
 }
 
 type Wiki struct {
-	Members []*Member `json:"members"`
-	Bio     []string  `json:"bio"`
-	Refs    []*Ref    `json:"refs"`
+	Members    []*Member         `json:"members"`
+	Bio        []string          `json:"bio"`
+	BioCompact string            `json:"bio_compact,omitempty"`
+	Refs       []*Ref            `json:"refs"`
+	Links      map[string]string `json:"links,omitempty"`
+	Author     string            `json:"author,omitempty"`
+	Published  *time.Time        `json:"published,omitempty"`
+}
+
+// attributionMaxLen is the maximum length of the wiki attribution line, so the longer
+// bio sentences are not taken as the attribution.
+const attributionMaxLen = 120
+
+var (
+	// authorRe matches the wiki attribution line, i.e. "Edited by User on 12 Mar 2020".
+	authorRe = regexp.MustCompile(`(?i)^(?:last\s+)?(?:edited|written|added)\s+by\s+([^.,;]+?)(?:\s+on\s+(.+?))?\.?$`)
+	// publishedRe matches the wiki published date line, i.e. "Published on 12 Mar 2020".
+	publishedRe = regexp.MustCompile(`(?i)^(?:published|(?:last\s+)?(?:edited|updated))\s+(?:on\s+)?(.+?)\.?$`)
+)
+
+// setAttribution function sets the wiki author and the published date from the wiki
+// content paragraph and reports whether the paragraph is the attribution line. The date,
+// if any, must be parsed, so the bio sentences are kept in the bio.
+func setAttribution(wiki *Wiki, txt string) bool {
+
+	if txt == "" || len(txt) > attributionMaxLen {
+		return false
+	}
+
+	var author, date string
+
+	if m := authorRe.FindStringSubmatch(txt); m != nil {
+		author, date = m[1], m[2]
+	} else if m := publishedRe.FindStringSubmatch(txt); m != nil {
+		date = m[1]
+	} else {
+		return false
+	}
+
+	var published *time.Time
+
+	if date != "" {
+		t, ok := parseEventDate(date)
+		if !ok {
+			return false
+		}
+		published = &t
+	}
+
+	if author != "" {
+		wiki.Author = author
+	}
+
+	if published != nil {
+		wiki.Published = published
+	}
+
+	return true
 }
 
 type Ref struct {
@@ -444,28 +1592,19 @@ type Member struct {
 	YearsActive string `json:"years_active"`
 }
 
-func readWiki(ctx context.Context, bandName string) (*Wiki, error) {
+func (c *Client) readWiki(ctx context.Context, bandName string) (*Wiki, error) {
 
 	if bandName == "" {
 		return nil, fmt.Errorf("read_wiki: band name is required")
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(wikiURL, bandName), nil)
-	if err != nil {
-		return nil, fmt.Errorf("read_wiki: new_request_with_context", err)
-	}
-
-	resp, err := defaultClient.Do(req)
+	resp, err := c.fetch(ctx, wikiPath, bandName)
 	if err != nil {
-		return nil, fmt.Errorf("read_wiki: http_get: %v", err)
+		return nil, fmt.Errorf("read_wiki: %w", err)
 	}
 
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("read_wiki: status: %s (%+v)", resp.Status, resp.Header)
-	}
-
 	var (
 		wiki      = new(Wiki)
 		txt       string
@@ -479,15 +1618,15 @@ func readWiki(ctx context.Context, bandName string) (*Wiki, error) {
 		switch tok {
 		case html.EndTagToken:
 			if startWiki {
-				if containsAttr(tokenizer, TagAttr("ul", "")) != "" {
+				if htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("ul", "")) != "" {
 					startWiki = false
 				}
 			}
 		case html.StartTagToken:
-			switch containsAttr(tokenizer,
-				TagAttr("ul", "class", "factbox"),
-				TagAttr("div", "class", "wiki-content"),
-				TagAttr("h4", "class", "factbox-heading")) {
+			switch htmlq.ContainsAttr(tokenizer,
+				htmlq.TagAttr("ul", "class", "factbox"),
+				htmlq.TagAttr("div", "class", "wiki-content"),
+				htmlq.TagAttr("h4", "class", "factbox-heading")) {
 
 			case "factbox":
 
@@ -503,49 +1642,93 @@ func readWiki(ctx context.Context, bandName string) (*Wiki, error) {
 					continue
 				}
 
-				title := string(tokenizer.Text())
-				if title != "Members" {
-					continue
-				}
+				switch title := htmlq.NormalizeSpace(string(tokenizer.Text())); title {
+				case "Members":
 
-				for next := tokenizer.Next(); tokenizer.Err() == nil; next = tokenizer.Next() {
+					for next := tokenizer.Next(); tokenizer.Err() == nil; next = tokenizer.Next() {
 
-					if next == html.EndTagToken && containsAttr(tokenizer, TagAttr("ul", "")) != "" {
-						break
-					}
+						if next == html.EndTagToken && htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("ul", "")) != "" {
+							break
+						}
 
-					if next != html.TextToken {
-						continue
-					}
+						if next != html.TextToken {
+							continue
+						}
 
-					if txt = strings.TrimSpace(string(tokenizer.Text())); txt == "" {
-						continue
+						if txt = htmlq.NormalizeSpace(string(tokenizer.Text())); txt == "" {
+							continue
+						}
+
+						if strings.HasPrefix(txt, "(") && len(wiki.Members) > 0 {
+							wiki.Members[len(wiki.Members)-1].YearsActive = txt
+						} else {
+							wiki.Members = append(wiki.Members, &Member{Name: txt})
+						}
 					}
 
-					if strings.HasPrefix(txt, "(") && len(wiki.Members) > 0 {
-						wiki.Members[len(wiki.Members)-1].YearsActive = txt
-					} else {
-						wiki.Members = append(wiki.Members, &Member{Name: txt})
+				case "External Links", "Links":
+
+					var (
+						href     string
+						hrefSeen = make(map[string]bool)
+					)
+
+					for next := tokenizer.Next(); tokenizer.Err() == nil; next = tokenizer.Next() {
+
+						if next == html.EndTagToken && htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("ul", "")) != "" {
+							break
+						}
+
+						if next == html.StartTagToken && htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("a", "")) != "" {
+							// we didn't read attributes, so can setup and iterator.
+							for iter := htmlq.NewIter(tokenizer); iter.Next(); {
+								if key, val := iter.Attrs(); key == "href" {
+									href = val
+									break
+								}
+							}
+							continue
+						}
+
+						if next != html.TextToken || href == "" {
+							continue
+						}
+
+						if txt = htmlq.NormalizeSpace(string(tokenizer.Text())); txt == "" {
+							continue
+						}
+
+						if isExternalLink(href) && !hrefSeen[href] {
+							if wiki.Links == nil {
+								wiki.Links = make(map[string]string)
+							}
+							if _, ok := wiki.Links[txt]; !ok {
+								wiki.Links[txt], hrefSeen[href] = href, true
+							}
+						}
+
+						href = ""
 					}
 				}
 
 			case "wiki-content":
 
 				var (
-					bio       []string
-					refsSeen  = make(map[string]string)
-					quote, br bool
-					txt, ref  string
+					bio, plain []string
+					refsSeen   = make(map[string]string)
+					quote, br  bool
+					txt, ref   string
+					refsLen    int
 				)
 
 			readbio_loop:
 				for next := tokenizer.Next(); tokenizer.Err() == nil; next = tokenizer.Next() {
 
-					switch containsAttr(tokenizer,
-						TagAttr("p", ""),
-						TagAttr("div", ""),
-						TagAttr("br", ""),
-						TagAttr("a", "")) {
+					switch htmlq.ContainsAttr(tokenizer,
+						htmlq.TagAttr("p", ""),
+						htmlq.TagAttr("div", ""),
+						htmlq.TagAttr("br", ""),
+						htmlq.TagAttr("a", "")) {
 
 					case "div":
 
@@ -558,10 +1741,22 @@ func readWiki(ctx context.Context, bandName string) (*Wiki, error) {
 						if next != html.EndTagToken {
 							continue
 						}
-						if len(bio) > 0 {
-							wiki.Bio, bio = append(wiki.Bio, strings.Split(strings.TrimSpace(strings.Join(bio, "")), "\n")...), nil
+
+						// the attribution paragraph is not the bio, its user link is not the reference.
+						if setAttribution(wiki, htmlq.NormalizeSpace(strings.Join(plain, ""))) {
+							for _, ref := range wiki.Refs[refsLen:] {
+								delete(refsSeen, ref.Name)
+							}
+							wiki.Refs = wiki.Refs[:refsLen]
+						} else {
+							// the line breaks are kept, so the lines are normalized one by one.
+							for _, line := range strings.Split(strings.TrimSpace(strings.Join(bio, "")), "\n") {
+								wiki.Bio = append(wiki.Bio, htmlq.NormalizeSpace(line))
+							}
 						}
 
+						bio, plain, refsLen = nil, nil, len(wiki.Refs)
+
 						continue
 
 					case "br":
@@ -577,7 +1772,7 @@ func readWiki(ctx context.Context, bandName string) (*Wiki, error) {
 						quote = true
 
 						// we didn't read attributes, so can setup and iterator.
-						for iter := NewIter(tokenizer); iter.Next(); {
+						for iter := htmlq.NewIter(tokenizer); iter.Next(); {
 							if key, val := iter.Attrs(); key == "href" {
 								ref = val
 								break
@@ -593,9 +1788,19 @@ func readWiki(ctx context.Context, bandName string) (*Wiki, error) {
 						continue
 					}
 
+					// the source newlines are not the line breaks, only the <br> tags are.
+					txt = strings.Map(func(r rune) rune {
+						if unicode.IsSpace(r) {
+							return ' '
+						}
+						return r
+					}, txt)
+
 					if ref != "" {
-						if _, seen := refsSeen[txt]; !seen {
-							wiki.Refs, refsSeen[txt] = append(wiki.Refs, &Ref{Name: txt, Reference: ref}), ref
+						if name := htmlq.NormalizeSpace(txt); name != "" {
+							if _, seen := refsSeen[name]; !seen {
+								wiki.Refs, refsSeen[name] = append(wiki.Refs, &Ref{Name: name, Reference: ref}), ref
+							}
 						}
 					}
 
@@ -603,8 +1808,10 @@ func readWiki(ctx context.Context, bandName string) (*Wiki, error) {
 						bio[len(bio)-1] += "\n"
 					}
 
+					plain = append(plain, txt)
+
 					if quote {
-						txt = fmt.Sprintf(refFormat, txt)
+						txt = fmt.Sprintf(c.cfg.RefFormat, txt)
 					}
 
 					bio, br, quote, ref = append(bio, txt), false, false, ""
@@ -614,116 +1821,284 @@ func readWiki(ctx context.Context, bandName string) (*Wiki, error) {
 	}
 
 	if err := tokenizer.Err(); err != nil && err != io.EOF {
-		return nil, fmt.Errorf("read_wiki: tokenizer: %v", err)
+		return nil, fmt.Errorf("read_wiki: tokenizer: %w", err)
+	}
+
+	if c.cfg.WikiCompact {
+		wiki.BioCompact = compactBio(wiki.Bio)
 	}
 
 	return wiki, nil
 }
 
-func readSimilarArtistsPage(ctx context.Context, bandName string, pageNum int) ([]string, error) {
+// readSimilarArtistsPage function reads the similar artists page and returns the
+// artists and the total number of the similar artists, or zero if the page has none.
+func (c *Client) readSimilarArtistsPage(ctx context.Context, bandName string, pageNum int) ([]*SimilarArtist, int, error) {
 
 	if bandName == "" {
-		return nil, fmt.Errorf("read_similar_artists: page %d: band name is required", pageNum)
+		return nil, 0, fmt.Errorf("read_similar_artists: page %d: band name is required", pageNum)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(similarArtistsPageURL, bandName, pageNum), nil)
+	resp, err := c.fetch(ctx, similarArtistsPagePath, bandName, pageNum)
 	if err != nil {
-		return nil, fmt.Errorf("read_similar_artists: page %d: new_request_with_context: %v", pageNum, err)
+		return nil, 0, fmt.Errorf("read_similar_artists: page %d: %w", pageNum, err)
+	}
+
+	defer resp.Body.Close()
+
+	// check page number in case of overflow.
+	if resp.Request.URL.Query().Get("page") != strconv.Itoa(pageNum) {
+		return nil, 0, nil
 	}
 
-	resp, err := defaultClient.Do(req)
+	// the page is parsed once per layout, so it is read in advance.
+	b, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read_similar_artists: page %d: http_get: %v", pageNum, err)
+		return nil, 0, fmt.Errorf("read_similar_artists: page %d: %w", pageNum, err)
 	}
 
-	defer resp.Body.Close()
+	var total int
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("read_similar_artists: status: %s (%+v)", resp.Status, resp.Header)
-	}
+	for _, layout := range similarLayouts {
 
-	// check page number in case of overflow.
-	if resp.Request.URL.Query().Get("page") != strconv.Itoa(pageNum) {
-		return nil, nil
+		page, ok, err := parseSimilarArtists(ctx, b, layout)
+		if err != nil {
+			return nil, 0, fmt.Errorf("read_similar_artists: page %d: tokenizer: %w", pageNum, err)
+		}
+
+		if ok {
+			if c.cfg.Verbose {
+				log.Printf("read_similar_artists: page %d: %s layout", pageNum, layout.name)
+			}
+			return page.similar, page.total, nil
+		}
+
+		total = page.total
 	}
 
-	tokenizer := html.NewTokenizer(resp.Body)
+	return nil, total, nil
+}
+
+// similarTotalRe matches the total number of the similar artists, i.e. "250 similar artists".
+var similarTotalRe = regexp.MustCompile(`(?i)^([\d,.]+[KM]?)\s+similar\s+artists?$`)
+
+// similarLayout is the similar artists list markup, last.fm serves the list in the
+// different containers over time and in the A/B tests.
+type similarLayout struct {
+	// name is the layout name for the -verbose log.
+	name string
+	// listTag and listClass are the list container, the cards are read until the list end.
+	listTag, listClass string
+	// nameClass, listenersClass and matchClass are the card a, p and span classes, the
+	// empty class is not read.
+	nameClass, listenersClass, matchClass string
+}
+
+// similarLayouts is the list of known similar artists layouts, the first layout with
+// the list container on the page is used.
+var similarLayouts = []similarLayout{
+	{
+		name:    "list",
+		listTag: "ol", listClass: "similar-artists",
+		nameClass:      "link-block-target",
+		listenersClass: "similar-artists-item-listeners",
+		matchClass:     "similar-artists-item-match",
+	},
+	{
+		name:    "grid",
+		listTag: "ol", listClass: "grid-items",
+		nameClass:      "grid-items-item-main-text",
+		listenersClass: "grid-items-item-aux-text",
+	},
+}
+
+// parseSimilarArtists function parses the similar artists cards of the layout and the
+// total number of the similar artists before the list, if any, and reports whether the
+// page has the layout list container. The buffered page parse stops with the context
+// error once the context is done.
+func parseSimilarArtists(ctx context.Context, data []byte, layout similarLayout) (*similarResult, bool, error) {
+
+	tokenizer := html.NewTokenizer(&contextBody{ReadCloser: io.NopCloser(bytes.NewReader(data)), ctx: ctx})
 
 	var (
-		similar      []string
+		similar      []*SimilarArtist
+		total        int
 		startSimilar bool
+		image        string
+		tags         = []*htmlq.Tag{htmlq.TagAttr("a", "class", layout.nameClass), htmlq.TagAttr("img", "")}
 	)
 
+	if layout.listenersClass != "" {
+		tags = append(tags, htmlq.TagAttr("p", "class", layout.listenersClass))
+	}
+
+	if layout.matchClass != "" {
+		tags = append(tags, htmlq.TagAttr("span", "class", layout.matchClass))
+	}
+
 	for tok := tokenizer.Next(); tokenizer.Err() == nil; tok = tokenizer.Next() {
 
 		switch tok {
 		case html.EndTagToken:
-			if startSimilar {
-				if containsAttr(tokenizer, TagAttr("ol", "")) != "" {
-					if startSimilar {
-						startSimilar = false
-					}
+			// the cards are read from the first list only, e.g. not from the similar tags grid.
+			if startSimilar && htmlq.ContainsAttr(tokenizer, htmlq.TagAttr(layout.listTag, "")) != "" {
+				if err := ctx.Err(); err != nil {
+					return nil, false, err
 				}
+				return &similarResult{similar, total}, true, nil
 			}
-		case html.StartTagToken:
+		case html.TextToken:
+			// the total heading precedes the list, i.e. "250 similar artists".
+			if !startSimilar && total == 0 {
+				if m := similarTotalRe.FindStringSubmatch(htmlq.NormalizeSpace(string(tokenizer.Text()))); m != nil {
+					total = parseAbbr(m[1])
+				}
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
 			if startSimilar {
-				if containsAttr(tokenizer, TagAttr("a", "class", "link-block-target")) != "" {
-					if tokenizer.Next() != html.TextToken {
+
+				switch attr, iter := htmlq.MatchAttr(tokenizer, tags...); attr {
+				case "":
+					// noop.
+				case "img":
+					// the thumbnail precedes the artist name in the card.
+					image = imageURL(iter)
+				case layout.nameClass:
+					if txt := htmlq.ReadText(tokenizer, "a"); txt != "" {
+						similar = append(similar, &SimilarArtist{Name: txt, Image: image})
+					}
+					image = ""
+				default:
+
+					// the listeners and match follow the artist name in the card.
+					if len(similar) == 0 || tokenizer.Next() != html.TextToken {
 						continue
 					}
-					if startSimilar {
-						similar = append(similar, string(tokenizer.Text()))
+
+					artist := similar[len(similar)-1]
+
+					switch txt := string(tokenizer.Text()); attr {
+					case layout.listenersClass:
+						artist.Listeners = parseCount(txt)
+					case layout.matchClass:
+						// match has the "87% match" format.
+						artist.Match = parseCount(strings.ReplaceAll(txt, "%", " "))
 					}
 				}
+
 			} else {
-				if containsAttr(tokenizer, TagAttr("ol", "class", "similar-artists")) != "" {
+				if htmlq.ContainsAttr(tokenizer, htmlq.TagAttr(layout.listTag, "class", layout.listClass)) != "" {
 					startSimilar = true
 				}
 			}
 		}
 	}
 
-	if tokenizer.Err() != io.EOF {
-		return nil, fmt.Errorf("read_similar_artists: page %d: tokenizer: %v", pageNum, err)
+	if err := tokenizer.Err(); err != nil && err != io.EOF {
+		return nil, false, err
+	}
+
+	return &similarResult{similar, total}, startSimilar, nil
+}
+
+// genre function returns the primary genre, the first editorial top tag or the first
+// community tag, the tags are in the weight order.
+func (r *TagsResult) genre() string {
+	for _, tags := range [][]string{r.TopTags, r.Tags} {
+		if len(tags) > 0 {
+			return tags[0]
+		}
+	}
+	return ""
+}
+
+// TagsResult is the content of the artist tags pages.
+type TagsResult struct {
+	// TopTags is the editorial genre/style tags list from the first page.
+	TopTags []string
+	// Tags is the community (user) tags list.
+	Tags []string
+	// SimilarArtists is the similar artists sidebar from the first page.
+	SimilarArtists []string
+	// RelatedTags is the related tags sidebar from the first page.
+	RelatedTags []string
+}
+
+// readTags function reads the -tags-pages pages of tags, deduplicated across the pages,
+// and the top tags, similar artists and related tags sidebars from the first page.
+func (c *Client) readTags(ctx context.Context, bandName string) (*TagsResult, error) {
+
+	var (
+		ret   = &TagsResult{TopTags: []string{}, Tags: []string{}, SimilarArtists: []string{}, RelatedTags: []string{}}
+		seen  = make(map[string]bool)
+		first *TagsResult
+	)
+
+	tags, err := readPages(ctx, c, func(ctx context.Context, pageNum int) ([]string, error) {
+		page, err := c.readTagsPage(ctx, bandName, pageNum)
+		if err != nil {
+			return nil, err
+		}
+		// the sidebars are read from the first page only.
+		if pageNum == 1 {
+			first = page
+		}
+		return page.Tags, nil
+	}, c.cfg.TagsPages, 0)
+	if err != nil {
+		return nil, fmt.Errorf("read_tags: %w", err)
+	}
+
+	// the sidebars are kept even if the band has no tags.
+	if first != nil {
+		ret.TopTags, ret.SimilarArtists, ret.RelatedTags = first.TopTags, first.SimilarArtists, first.RelatedTags
+	}
+
+	for _, tag := range tags {
+		if !seen[tag] {
+			ret.Tags, seen[tag] = append(ret.Tags, tag), true
+		}
 	}
 
-	return similar, nil
+	return ret, nil
 }
 
-func readTags(bandName string) ([]string, []string, error) {
+func (c *Client) readTagsPage(ctx context.Context, bandName string, pageNum int) (*TagsResult, error) {
 
 	if bandName == "" {
-		return nil, nil, fmt.Errorf("read_tags: band name is required")
+		return nil, fmt.Errorf("page %d: band name is required", pageNum)
 	}
 
-	resp, err := http.Get(fmt.Sprintf(tagsURL, bandName))
+	resp, err := c.fetch(ctx, tagsPagePath, bandName, pageNum)
 	if err != nil {
-		return nil, nil, fmt.Errorf("read_tags: http_get: %v", err)
+		return nil, fmt.Errorf("page %d: %w", pageNum, err)
 	}
 
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, nil, fmt.Errorf("read_tags: status: %s", resp.Status)
+	// check page number in case of overflow.
+	if pageNum > 1 && resp.Request.URL.Query().Get("page") != strconv.Itoa(pageNum) {
+		return &TagsResult{}, nil
 	}
 
 	tokenizer := html.NewTokenizer(resp.Body)
 
 	var (
-		tags, similar = []string{}, []string{}
-		startTags     bool
-		startSimilar  bool
+		page                                  = &TagsResult{TopTags: []string{}, Tags: []string{}, SimilarArtists: []string{}, RelatedTags: []string{}}
+		startTags, startSimilar, startRelated bool
+		startTop                              bool
 	)
 
-	numEntites := 3
+	// the top tags list is optional, so pages without it are read to the end.
+	numEntites := 4
 
 loop:
 	for tok := tokenizer.Next(); tokenizer.Err() == nil; tok = tokenizer.Next() {
 
 		switch tok {
 		case html.EndTagToken:
-			if startTags || startSimilar {
-				if containsAttr(tokenizer, TagAttr("ol", "")) != "" {
+			if startTags || startSimilar || startRelated || startTop {
+				if htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("ol", "")) != "" {
 					if startTags {
 						numEntites--
 						startTags = false
@@ -732,6 +2107,14 @@ loop:
 						numEntites--
 						startSimilar = false
 					}
+					if startRelated {
+						numEntites--
+						startRelated = false
+					}
+					if startTop {
+						numEntites--
+						startTop = false
+					}
 				}
 
 				if numEntites == 0 {
@@ -739,116 +2122,315 @@ loop:
 				}
 			}
 		case html.StartTagToken:
-			if startTags || startSimilar {
-				if containsAttr(tokenizer, TagAttr("a", "class", "link-block-target")) != "" {
-					if tokenizer.Next() != html.TextToken {
+			if startTags || startSimilar || startRelated || startTop {
+
+				// related and top tags are plain links.
+				link := htmlq.TagAttr("a", "class", "link-block-target")
+				if startRelated || startTop {
+					link = htmlq.TagAttr("a", "")
+				}
+
+				if htmlq.ContainsAttr(tokenizer, link) != "" {
+
+					txt := htmlq.ReadText(tokenizer, "a")
+					if txt == "" {
 						continue
 					}
-					if startTags {
-						tags = append(tags, string(tokenizer.Text()))
-					}
 
-					if startSimilar {
-						similar = append(similar, string(tokenizer.Text()))
+					switch {
+					case startTags:
+						page.Tags = append(page.Tags, txt)
+					case startSimilar:
+						page.SimilarArtists = append(page.SimilarArtists, txt)
+					case startRelated:
+						page.RelatedTags = append(page.RelatedTags, txt)
+					case startTop:
+						page.TopTags = append(page.TopTags, txt)
 					}
 				}
 			} else {
-				switch containsAttr(tokenizer,
-					TagAttr("ol", "class", "big-tags", "similar-items-sidebar")) {
+				switch htmlq.ContainsAttr(tokenizer,
+					htmlq.TagAttr("ol", "class", "big-tags", "similar-items-sidebar", "tags-list--related", "tags-list--global")) {
 				case "big-tags":
 					startTags = true
 				case "similar-items-sidebar":
 					startSimilar = true
+				case "tags-list--related":
+					startRelated = true
+				case "tags-list--global":
+					startTop = true
 				}
 			}
 		}
 	}
 
 	if err := tokenizer.Err(); err != nil && err != io.EOF {
-		return nil, nil, fmt.Errorf("read_tags: tokenizer: %v", err)
+		return nil, fmt.Errorf("page %d: tokenizer: %w", pageNum, err)
 	}
 
-	return tags, similar, nil
+	return page, nil
 }
 
-type tagAttr struct {
-	tagName  string
-	attrName string
-	attrVals []string
+// compactBio function returns the bio as one line, the lines and the paragraphs are
+// joined with a single space and the empty paragraphs are dropped.
+func compactBio(bio []string) string {
+	return htmlq.NormalizeSpace(strings.Join(bio, " "))
 }
 
-func TagAttr(tagName, attrName string, attrVals ...string) *tagAttr {
-	return &tagAttr{tagName, attrName, attrVals}
+// readSummary function reads the overview wiki teaser text until the end of the
+// enclosing div, without the "Read more on Last.fm" link.
+func readSummary(tokenizer *html.Tokenizer) string {
+
+	var (
+		txt   []string
+		depth = 1
+		cta   bool
+	)
+
+	for tok := tokenizer.Next(); tokenizer.Err() == nil && depth > 0; tok = tokenizer.Next() {
+		switch tok {
+		case html.StartTagToken:
+			switch htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("div", ""), htmlq.TagAttr("a", "class", "wiki-block-cta")) {
+			case "div":
+				depth++
+			case "wiki-block-cta":
+				cta = true
+			}
+		case html.EndTagToken:
+			switch htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("div", ""), htmlq.TagAttr("a", "")) {
+			case "div":
+				depth--
+			case "a":
+				cta = false
+			}
+		case html.TextToken:
+			if !cta {
+				txt = append(txt, string(tokenizer.Text()))
+			}
+		}
+	}
+
+	summary := htmlq.NormalizeSpace(strings.Join(txt, ""))
+
+	return strings.TrimSpace(strings.TrimSuffix(summary, "Read more on Last.fm"))
 }
 
-// containsAttr function will return matched attribute value or token name (if attribute value is omitted).
-func containsAttr(tokenizer *html.Tokenizer, tagAttrs ...*tagAttr) string {
+// splitLocation function splits the "City, Country" location on the last comma, the
+// single token location is the country.
+func splitLocation(s string) (string, string) {
+	if i := strings.LastIndex(s, ","); i >= 0 {
+		return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+	}
+	return "", strings.TrimSpace(s)
+}
 
-	tagName, hasAttr := tokenizer.TagName()
-	iter := NewIter(tokenizer)
+// bandNameFromURL function returns the band name from the last.fm /music/<band_name> url.
+func bandNameFromURL(u *url.URL) string {
+	slug, _, _ := strings.Cut(strings.TrimPrefix(u.EscapedPath(), "/music/"), "/")
+	if name, err := url.QueryUnescape(slug); err == nil {
+		return name
+	}
+	return slug
+}
 
-	for _, tagAttr := range tagAttrs {
-		if tagAttr.tagName != string(tagName) {
-			continue
-		}
+// isExternalLink function reports whether the link is an absolute non-last.fm url.
+func isExternalLink(href string) bool {
+	u, err := url.Parse(href)
+	return err == nil && u.IsAbs() && u.Hostname() != "last.fm" && !strings.HasSuffix(u.Hostname(), ".last.fm")
+}
+
+// parseCount function parses the formatted count, like "1,357,164" or "1,357,164 listeners".
+func parseCount(s string) int {
+	if fields := strings.Fields(s); len(fields) > 0 {
+		n, _ := strconv.Atoi(strings.ReplaceAll(fields[0], ",", ""))
+		return n
+	}
+	return 0
+}
 
-		if tagAttr.attrName == "" {
-			return tagAttr.tagName
+// readTrend function reads the weekly listeners trend, the up, down or flat direction
+// from the trend class modifier and the delta from the title, like "+1,234 listeners
+// this week", or the element text. The direction is derived from the delta if the
+// class has no modifier.
+func readTrend(tokenizer *html.Tokenizer, iter *htmlq.Iter) (string, int) {
+
+	var trend, delta string
+
+	for iter.Reset(); iter.Next(); {
+		switch key, val := iter.Attrs(); key {
+		case "class":
+			for _, class := range strings.Fields(val) {
+				if _, mod, ok := strings.Cut(class, "header-metadata-tnew-trend--"); ok {
+					trend = mod
+				}
+			}
+		case "title":
+			delta = val
 		}
+	}
+
+	if delta == "" {
+		delta = htmlq.ReadText(tokenizer, "span")
+	}
 
-		if !hasAttr {
-			return ""
+	// the minus sign may be the typographic one.
+	n := parseAbbr(strings.ReplaceAll(delta, "−", "-"))
+
+	if trend == "" {
+		switch {
+		case n > 0:
+			trend = "up"
+		case n < 0:
+			trend = "down"
+		case delta != "":
+			trend = "flat"
 		}
+	}
 
-		iter.Reset()
+	return trend, n
+}
 
-		for iter.Next() {
-			key, val := iter.Attrs()
-			if key != tagAttr.attrName {
-				continue
-			}
-			if len(tagAttr.attrVals) == 0 {
-				return tagAttr.attrName
-			}
-			if tagAttr.attrVals[0] == "*" {
+// parseAbbr function parses the count, which may be abbreviated, like "751.7K" or "4.5M",
+// the trailing unit word, like "listeners", is ignored.
+func parseAbbr(s string) int {
+
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0
+	}
+
+	num, mult := strings.ReplaceAll(fields[0], ",", ""), 1.0
+
+	switch {
+	case strings.HasSuffix(num, "K"):
+		num, mult = strings.TrimSuffix(num, "K"), 1e3
+	case strings.HasSuffix(num, "M"):
+		num, mult = strings.TrimSuffix(num, "M"), 1e6
+	case strings.HasSuffix(num, "B"):
+		num, mult = strings.TrimSuffix(num, "B"), 1e9
+	}
+
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+
+	return int(math.Round(n * mult))
+}
+
+// imageURL function returns the image url from the srcset (the largest candidate), src or content attributes.
+func imageURL(iter *htmlq.Iter) string {
+
+	var src string
+
+	for iter.Reset(); iter.Next(); {
+		switch key, val := iter.Attrs(); key {
+		case "srcset":
+			if val = largestSrcset(val); val != "" {
 				return val
 			}
-			for _, attrVal := range tagAttr.attrVals {
-				if strings.Contains(val, attrVal) {
-					return attrVal
-				}
+		case "src", "content":
+			if src == "" {
+				src = val
 			}
 		}
 	}
-	return ""
-}
 
-type iterTagAttr struct {
-	*html.Tokenizer
-	pos  int
-	keys []string
-	vals []string
+	return src
 }
 
-func NewIter(tokenizer *html.Tokenizer) *iterTagAttr {
-	return &iterTagAttr{Tokenizer: tokenizer, pos: -1}
-}
+// externalLink function returns the external store or stream link name and url of the
+// anchor, e.g. "spotify", or the empty url for the relative and the last.fm links. The
+// name is the data-playlink-affiliate attribute, or the url site name.
+func externalLink(iter *htmlq.Iter) (string, string) {
 
-func (i *iterTagAttr) Next() bool {
-	if i.pos++; i.Tokenizer != nil {
-		key, val, more := i.TagAttr()
-		if !more {
-			i.Tokenizer = nil
+	var name, href string
+
+	for iter.Reset(); iter.Next(); {
+		switch key, val := iter.Attrs(); key {
+		case "href":
+			href = val
+		case "data-playlink-affiliate":
+			name = strings.ToLower(strings.TrimSpace(val))
 		}
-		i.keys, i.vals = append(i.keys, string(key)), append(i.vals, string(val))
 	}
-	return i.pos < len(i.keys)
+
+	u, err := url.Parse(href)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return "", ""
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "last.fm" || strings.HasSuffix(host, ".last.fm") || strings.Contains(host, "lastfm") {
+		return "", ""
+	}
+
+	if name == "" {
+		name = siteName(host)
+	}
+
+	return name, u.String()
+}
+
+// siteName function returns the site name from the host, i.e. the label before the
+// top-level domain: open.spotify.com is spotify, amazon.co.uk is amazon.
+func siteName(host string) string {
+
+	labels := strings.Split(host, ".")
+
+	switch n := len(labels); {
+	case n < 2:
+		return host
+	case n > 2 && (labels[n-2] == "co" || labels[n-2] == "com"):
+		return labels[n-3]
+	default:
+		return labels[n-2]
+	}
 }
 
-func (i *iterTagAttr) Reset() {
-	i.pos = -1
+// addLink function adds the external link of the anchor to the links, the first link
+// of the site is kept.
+func addLink(links *map[string]string, iter *htmlq.Iter) {
+
+	name, href := externalLink(iter)
+	if href == "" {
+		return
+	}
+
+	if *links == nil {
+		*links = make(map[string]string)
+	}
+
+	if _, ok := (*links)[name]; !ok {
+		(*links)[name] = href
+	}
 }
 
-func (i *iterTagAttr) Attrs() (string, string) {
-	return i.keys[i.pos], i.vals[i.pos]
+// largestSrcset function returns the candidate url with the largest width or density descriptor.
+func largestSrcset(srcset string) string {
+
+	var (
+		ret  string
+		size float64
+	)
+
+	for _, candidate := range strings.Split(srcset, ",") {
+
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// no descriptor is same as 1x.
+		curSize := 1.0
+		if len(fields) > 1 {
+			curSize, _ = strconv.ParseFloat(strings.TrimRight(fields[1], "wx"), 64)
+		}
+
+		if ret == "" || curSize > size {
+			ret, size = fields[0], curSize
+		}
+	}
+
+	return ret
 }
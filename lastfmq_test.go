@@ -0,0 +1,1506 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oiweiwei/lastfmq/htmlq"
+	"golang.org/x/net/html"
+)
+
+// newFixtureClient function starts the server responding with the testdata fixtures
+// by the request uri, and returns the client pointed to it.
+func newFixtureClient(t *testing.T, fixtures map[string]string, opts ...Option) *Client {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fixture, ok := fixtures[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", fixture))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return NewClient(append([]Option{WithBaseURL(srv.URL), WithHTTPClient(srv.Client())}, opts...)...)
+}
+
+func TestReadOverview(t *testing.T) {
+
+	c := newFixtureClient(t, map[string]string{
+		"/music/Fugazi":       "overview.html",
+		"/music/Unknown+Band": "overview_minimal.html",
+		"/music/Ian+MacKaye":  "overview_born.html",
+		"/music/Soft+404":     "overview_soft404.html",
+		"/music/Minor+Threat": "overview_counts.html",
+		"/music/Fugazi+LD":    "overview_ld.html",
+	})
+
+	for _, tc := range []struct {
+		name     string
+		bandName string
+		expected *bandDesc
+	}{
+		{"full", "Fugazi", &bandDesc{
+			BandName:         "Fugazi",
+			Scrobbles:        18386097,
+			Listeners:        751721,
+			MonthlyListeners: 123456,
+			ReleaseCount:     12,
+			OnTour:           true,
+			YearsActive:      "1987 – present",
+			FoundedIn:        "Washington, District of Columbia, United States",
+			FoundedCity:      "Washington, District of Columbia",
+			FoundedCountry:   "United States",
+			ImageURL:         "https://lastfm.freetls.fastly.net/i/u/ar0/fugazi.jpg",
+			Summary:          "Fugazi is an American post-hardcore band that formed in Washington, D.C., in 1986. The band consists of guitarists and vocalists Ian MacKaye and Guy Picciotto…",
+			FeaturedTracks: []*Track{
+				{Title: "Waiting Room", Listeners: 512345},
+				{Title: "Merchandise", Listeners: 301002},
+			},
+		}},
+		{"json-ld", "Fugazi+LD", &bandDesc{
+			BandName: "Fugazi",
+			ImageURL: "https://lastfm.freetls.fastly.net/i/u/ar0/fugazi-ld.jpg",
+			Genre:    "post-hardcore",
+		}},
+		{"missing metadata", "Unknown+Band", &bandDesc{
+			BandName: "Unknown Band",
+		}},
+		{"counts", "Minor+Threat", &bandDesc{
+			BandName:         "Minor Threat",
+			Scrobbles:        14532198,
+			Listeners:        612400,
+			MonthlyListeners: 98765,
+			ReleaseCount:     1200,
+			ListenersTrend:   "up",
+			ListenersDelta:   1234,
+		}},
+		{"nested metadata", "Ian+MacKaye", &bandDesc{
+			BandName:    "Ian MacKaye",
+			Born:        "16 April 1962 (age 63)",
+			BornIn:      "Washington, District of Columbia, United States",
+			BornCity:    "Washington, District of Columbia",
+			BornCountry: "United States",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			desc, err := c.readOverview(context.Background(), tc.bandName)
+			if err != nil {
+				t.Fatalf("read_overview: %v", err)
+			}
+
+			if !reflect.DeepEqual(desc, tc.expected) {
+				t.Fatalf("read_overview: expected %+v, got %+v", tc.expected, desc)
+			}
+		})
+	}
+
+	t.Run("consent", func(t *testing.T) {
+		c := newFixtureClient(t, map[string]string{"/music/Fugazi": "consent.html"})
+		if _, err := c.readOverview(context.Background(), "Fugazi"); !errors.Is(err, ErrConsentRequired) {
+			t.Fatalf("read_overview: expected %v, got %v", ErrConsentRequired, err)
+		}
+	})
+
+	for _, bandName := range []string{"Nobody", "Soft+404"} {
+		t.Run("not found "+bandName, func(t *testing.T) {
+			if _, err := c.readOverview(context.Background(), bandName); !errors.Is(err, ErrBandNotFound) {
+				t.Fatalf("read_overview: expected %v, got %v", ErrBandNotFound, err)
+			}
+		})
+	}
+}
+
+func TestReadWiki(t *testing.T) {
+
+	c := newFixtureClient(t, map[string]string{
+		"/music/Fugazi/+wiki":       "wiki.html",
+		"/music/Unknown+Band/+wiki": "wiki_empty.html",
+	})
+
+	t.Run("full", func(t *testing.T) {
+
+		wiki, err := c.readWiki(context.Background(), "Fugazi")
+		if err != nil {
+			t.Fatalf("read_wiki: %v", err)
+		}
+
+		members := []*Member{
+			{Name: "Brendan Canty", YearsActive: "(1987 – present)"},
+			{Name: "Ian MacKaye", YearsActive: "(1987 – present)"},
+		}
+
+		if !reflect.DeepEqual(wiki.Members, members) {
+			t.Fatalf("read_wiki: expected members %+v, got %+v", members, wiki.Members)
+		}
+
+		links := map[string]string{
+			"Official website": "http://www.dischord.com/band/fugazi",
+			"Facebook":         "https://www.facebook.com/fugazi",
+			"Twitter":          "https://twitter.com/fugazi",
+		}
+
+		if !reflect.DeepEqual(wiki.Links, links) {
+			t.Fatalf("read_wiki: expected links %v, got %v", links, wiki.Links)
+		}
+
+		if len(wiki.Bio) == 0 {
+			t.Fatalf("read_wiki: expected bio, got none")
+		}
+	})
+
+	t.Run("no wiki", func(t *testing.T) {
+
+		wiki, err := c.readWiki(context.Background(), "Unknown+Band")
+		if err != nil {
+			t.Fatalf("read_wiki: %v", err)
+		}
+
+		if len(wiki.Members) != 0 || len(wiki.Bio) != 0 || len(wiki.Links) != 0 {
+			t.Fatalf("read_wiki: expected empty wiki, got %+v", wiki)
+		}
+	})
+}
+
+func TestReadTags(t *testing.T) {
+
+	fixtures := map[string]string{
+		"/music/Fugazi/+tags?page=1": "tags.html",
+		"/music/Fugazi/+tags?page=2": "tags_2.html",
+		"/music/Fugazi/+tags?page=3": "similar_empty.html",
+	}
+
+	for _, tc := range []struct {
+		name  string
+		pages int
+		tags  []string
+	}{
+		{"first page", 1, []string{"post-hardcore", "punk", "hardcore"}},
+		{"dedupe pages", 2, []string{"post-hardcore", "punk", "hardcore", "dc"}},
+		{"stop on empty page", 5, []string{"post-hardcore", "punk", "hardcore", "dc"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			cfg := DefaultConfig()
+			cfg.TagsPages = tc.pages
+
+			c := newFixtureClient(t, fixtures, WithConfig(cfg))
+
+			page, err := c.readTags(context.Background(), "Fugazi")
+			if err != nil {
+				t.Fatalf("read_tags: %v", err)
+			}
+
+			if !slices.Equal(page.Tags, tc.tags) {
+				t.Fatalf("read_tags: expected tags %q, got %q", tc.tags, page.Tags)
+			}
+
+			if expected := []string{"Minor Threat", "Rites of Spring"}; !slices.Equal(page.SimilarArtists, expected) {
+				t.Fatalf("read_tags: expected similar %q, got %q", expected, page.SimilarArtists)
+			}
+
+			if expected := []string{"emo", "math rock"}; !slices.Equal(page.RelatedTags, expected) {
+				t.Fatalf("read_tags: expected related %q, got %q", expected, page.RelatedTags)
+			}
+		})
+	}
+}
+
+func TestReadTagsSidebars(t *testing.T) {
+
+	c := newFixtureClient(t, map[string]string{"/music/Fugazi/+tags?page=1": "tags_sidebars.html"})
+
+	page, err := c.readTags(context.Background(), "Fugazi")
+	if err != nil {
+		t.Fatalf("read_tags: %v", err)
+	}
+
+	if len(page.Tags) != 0 {
+		t.Fatalf("read_tags: expected no tags, got %q", page.Tags)
+	}
+
+	if expected := []string{"Minor Threat", "Rites of Spring"}; !slices.Equal(page.SimilarArtists, expected) {
+		t.Fatalf("read_tags: expected similar %q, got %q", expected, page.SimilarArtists)
+	}
+
+	if expected := []string{"emo", "math rock"}; !slices.Equal(page.RelatedTags, expected) {
+		t.Fatalf("read_tags: expected related %q, got %q", expected, page.RelatedTags)
+	}
+}
+
+func TestReadTagsTop(t *testing.T) {
+
+	for _, tc := range []struct {
+		name    string
+		fixture string
+		top     []string
+	}{
+		{"top and user tags", "tags_top.html", []string{"post-hardcore", "alternative"}},
+		{"user tags only", "tags.html", []string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			c := newFixtureClient(t, map[string]string{"/music/Fugazi/+tags?page=1": tc.fixture})
+
+			page, err := c.readTags(context.Background(), "Fugazi")
+			if err != nil {
+				t.Fatalf("read_tags: %v", err)
+			}
+
+			if !slices.Equal(page.TopTags, tc.top) {
+				t.Fatalf("read_tags: expected top tags %q, got %q", tc.top, page.TopTags)
+			}
+
+			if expected := []string{"post-hardcore", "punk", "hardcore"}; !slices.Equal(page.Tags, expected) {
+				t.Fatalf("read_tags: expected tags %q, got %q", expected, page.Tags)
+			}
+
+			if genre := page.genre(); genre != "post-hardcore" {
+				t.Fatalf("read_tags: expected post-hardcore genre, got %q", genre)
+			}
+		})
+	}
+
+	if genre := (&TagsResult{RelatedTags: []string{"emo"}}).genre(); genre != "" {
+		t.Fatalf("read_tags: expected no genre without tags, got %q", genre)
+	}
+}
+
+func TestReadSimilarArtists(t *testing.T) {
+
+	c := newFixtureClient(t, map[string]string{
+		"/music/Fugazi/+similar?page=1":       "similar_1.html",
+		"/music/Fugazi/+similar?page=2":       "similar_2.html",
+		"/music/Fugazi/+similar?page=3":       "similar_3.html",
+		"/music/Unknown+Band/+similar?page=1": "similar_empty.html",
+	})
+
+	for _, tc := range []struct {
+		name          string
+		bandName      string
+		pages, offset int
+		size          int
+		first         string
+	}{
+		{"first page", "Fugazi", 1, 0, 10, "Unwound"},
+		{"all pages", "Fugazi", 3, 0, 24, "Unwound"},
+		{"nested markup", "Fugazi", 1, 2, 4, "No Knife"},
+		{"empty page", "Unknown+Band", 1, 0, 0, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			artists, _, err := c.readSimilarArtists(context.Background(), tc.bandName, tc.pages, tc.offset)
+			if err != nil {
+				t.Fatalf("read_similar_artists: %v", err)
+			}
+
+			similar := similarNames(artists)
+
+			if len(similar) != tc.size {
+				t.Fatalf("read_similar_artists: expected %d artists, got %d", tc.size, len(similar))
+			}
+
+			if len(similar) > 0 && similar[0] != tc.first {
+				t.Fatalf("read_similar_artists: expected %q first, got %q", tc.first, similar[0])
+			}
+
+			for _, name := range similar {
+				if name == "" || name == "Narrow" {
+					t.Fatalf("read_similar_artists: truncated name in %q", similar)
+				}
+			}
+		})
+	}
+}
+
+func TestReadSimilarArtistsMatch(t *testing.T) {
+
+	c := newFixtureClient(t, map[string]string{
+		"/music/Fugazi/+similar?page=1": "similar_1.html",
+	})
+
+	similar, _, err := c.readSimilarArtists(context.Background(), "Fugazi", 1, 0)
+	if err != nil {
+		t.Fatalf("read_similar_artists: %v", err)
+	}
+
+	expected := []SimilarArtist{
+		{Name: "Unwound", Match: 100, Listeners: 412345, Image: "https://lastfm.freetls.fastly.net/i/u/avatar170s/unwound.jpg"},
+		{Name: "Rites of Spring", Match: 87, Listeners: 289012, Image: "https://lastfm.freetls.fastly.net/i/u/avatar70s/rites.jpg"},
+		{Name: "Squirrel Bait"},
+	}
+
+	for i, artist := range expected {
+		if *similar[i] != artist {
+			t.Fatalf("read_similar_artists: expected %+v, got %+v", artist, *similar[i])
+		}
+	}
+}
+
+func TestReadSimilarArtistsLayouts(t *testing.T) {
+
+	for _, tc := range []struct {
+		fixture  string
+		expected []SimilarArtist
+	}{
+		{"similar_1.html", []SimilarArtist{
+			{Name: "Unwound", Match: 100, Listeners: 412345, Image: "https://lastfm.freetls.fastly.net/i/u/avatar170s/unwound.jpg"},
+			{Name: "Rites of Spring", Match: 87, Listeners: 289012, Image: "https://lastfm.freetls.fastly.net/i/u/avatar70s/rites.jpg"},
+		}},
+		{"similar_grid.html", []SimilarArtist{
+			{Name: "Unwound", Listeners: 412345, Image: "https://lastfm.freetls.fastly.net/i/u/300x300/unwound.jpg"},
+			{Name: "Rites of Spring", Listeners: 289012},
+		}},
+	} {
+		t.Run(tc.fixture, func(t *testing.T) {
+
+			c := newFixtureClient(t, map[string]string{"/music/Fugazi/+similar?page=1": tc.fixture})
+
+			similar, _, err := c.readSimilarArtistsPage(context.Background(), "Fugazi", 1)
+			if err != nil {
+				t.Fatalf("read_similar_artists: %v", err)
+			}
+
+			// the grid layout list ends before the similar tags grid.
+			if tc.fixture == "similar_grid.html" && len(similar) != len(tc.expected) {
+				t.Fatalf("read_similar_artists: expected %d artists, got %d", len(tc.expected), len(similar))
+			}
+
+			for i, artist := range tc.expected {
+				if i >= len(similar) || *similar[i] != artist {
+					t.Fatalf("read_similar_artists: %d: expected %+v, got %+v", i, artist, *similar[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSortSimilar(t *testing.T) {
+
+	for _, tc := range []struct {
+		order    string
+		expected []string
+	}{
+		{"none", []string{"unwound", "Rites of Spring", "Jawbox", "Minor Threat"}},
+		{"name", []string{"Jawbox", "Minor Threat", "Rites of Spring", "unwound"}},
+		{"count", []string{"Minor Threat", "Rites of Spring", "unwound", "Jawbox"}},
+		{"match", []string{"unwound", "Rites of Spring", "Jawbox", "Minor Threat"}},
+	} {
+		t.Run(tc.order, func(t *testing.T) {
+
+			similar := []*SimilarArtist{
+				{Name: "unwound", Match: 100, Listeners: 400},
+				{Name: "Rites of Spring", Match: 87, Listeners: 500},
+				{Name: "Jawbox", Match: 87},
+				{Name: "Minor Threat", Listeners: 900},
+			}
+
+			if sortSimilar(similar, tc.order); !slices.Equal(similarNames(similar), tc.expected) {
+				t.Fatalf("sort_similar: expected %q, got %q", tc.expected, similarNames(similar))
+			}
+		})
+	}
+}
+
+func TestFilterSimilar(t *testing.T) {
+
+	for _, tc := range []struct {
+		minMatch float64
+		expected []string
+	}{
+		{0, []string{"unwound", "Rites of Spring", "Jawbox", "Minor Threat"}},
+		{0.8, []string{"unwound", "Rites of Spring"}},
+		{0.87, []string{"unwound", "Rites of Spring"}},
+		{1, []string{"unwound"}},
+	} {
+		similar := []*SimilarArtist{
+			{Name: "unwound", Match: 100},
+			{Name: "Rites of Spring", Match: 87},
+			{Name: "Jawbox", Match: 42},
+			{Name: "Minor Threat"},
+		}
+
+		if actual := similarNames(filterSimilar(similar, tc.minMatch)); !slices.Equal(actual, tc.expected) {
+			t.Errorf("filter_similar: %v: expected %q, got %q", tc.minMatch, tc.expected, actual)
+		}
+	}
+}
+
+func TestReadSimilarArtistsCanceled(t *testing.T) {
+
+	var requests atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.ServeFile(w, r, filepath.Join("testdata", "similar_1.html"))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	c := NewClient(WithBaseURL(srv.URL), WithHTTPClient(srv.Client()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, _, err := c.readSimilarArtists(ctx, "Fugazi", 5, 0); !errors.Is(err, context.Canceled) {
+		t.Fatalf("read_similar_artists: expected canceled, got %v", err)
+	}
+
+	if n := requests.Load(); n != 0 {
+		t.Fatalf("read_similar_artists: expected no requests, got %d", n)
+	}
+}
+
+func TestReadSimilarArtistsAsyncOrder(t *testing.T) {
+
+	cfg := DefaultConfig()
+	cfg.Workers = 3
+
+	c := newFixtureClient(t, map[string]string{
+		"/music/Fugazi/+similar?page=1": "similar_1.html",
+		"/music/Fugazi/+similar?page=2": "similar_2.html",
+		"/music/Fugazi/+similar?page=3": "similar_3.html",
+	}, WithConfig(cfg))
+
+	gap := newFixtureClient(t, map[string]string{
+		"/music/Fugazi/+similar?page=1": "similar_1.html",
+		"/music/Fugazi/+similar?page=2": "similar_empty.html",
+		"/music/Fugazi/+similar?page=3": "similar_3.html",
+	}, WithConfig(cfg))
+
+	for _, tc := range []struct {
+		name          string
+		c             *Client
+		pages, offset int
+		size          int
+	}{
+		{"all pages", c, 3, 0, 24},
+		{"offset", c, 2, 1, 14},
+		{"empty page in the middle", gap, 3, 0, 14},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			c := tc.c
+
+			artists, _, err := c.readSimilarArtists(context.Background(), "Fugazi", tc.pages, tc.offset)
+			if err != nil {
+				t.Fatalf("read_similar_artists: %v", err)
+			}
+
+			sync := similarNames(artists)
+
+			if len(sync) != tc.size {
+				t.Fatalf("read_similar_artists: expected %d artists, got %d", tc.size, len(sync))
+			}
+
+			for i := 0; i < 10; i++ {
+
+				artists, _, err := c.readSimilarArtistsAsync(context.Background(), "Fugazi", tc.pages, tc.offset)
+				if err != nil {
+					t.Fatalf("read_similar_artists_async: %v", err)
+				}
+
+				async := similarNames(artists)
+
+				if !slices.Equal(sync, async) {
+					t.Fatalf("read_similar_artists_async: expected %q, got %q", sync, async)
+				}
+
+				if slices.Contains(async, "") {
+					t.Fatalf("read_similar_artists_async: empty artist in %q", async)
+				}
+			}
+		})
+	}
+}
+
+func TestReadSimilarArtistsPageTimeout(t *testing.T) {
+
+	var requests [3]atomic.Int32
+
+	// the page 2 hangs on the first request, the page 3 always.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if n := requests[page-1].Add(1); page == 3 || (page == 2 && n == 1) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", fmt.Sprintf("similar_%d.html", page)))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	cfg := DefaultConfig()
+	cfg.PageTimeout = 50 * time.Millisecond
+
+	c := NewClient(WithBaseURL(srv.URL), WithHTTPClient(srv.Client()), WithConfig(cfg))
+
+	similar, _, err := c.readSimilarArtists(context.Background(), "Fugazi", 2, 0)
+	if err != nil {
+		t.Fatalf("read_similar_artists: %v", err)
+	}
+
+	if len(similar) != 20 || requests[1].Load() != 2 {
+		t.Fatalf("read_similar_artists: expected 20 artists with the page 2 re-read, got %d, %d requests", len(similar), requests[1].Load())
+	}
+
+	start := time.Now()
+
+	if _, _, err = c.readSimilarArtists(context.Background(), "Fugazi", 1, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("read_similar_artists: expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second || requests[2].Load() != 2 {
+		t.Fatalf("read_similar_artists: expected the page 3 to fail fast after 2 requests, got %v, %d requests", elapsed, requests[2].Load())
+	}
+}
+
+func TestReadSimilarArtistsTotal(t *testing.T) {
+
+	var requests atomic.Int32
+
+	// the pages past the total fail, so must not be read.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page > 3 {
+			http.Error(w, "unexpected page", http.StatusInternalServerError)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", fmt.Sprintf("similar_%d.html", page)))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	cfg := DefaultConfig()
+	cfg.Workers = 3
+
+	c := NewClient(WithBaseURL(srv.URL), WithHTTPClient(srv.Client()), WithConfig(cfg))
+
+	for name, read := range map[string]func(context.Context, string, int, int) ([]*SimilarArtist, int, error){
+		"sync":  c.readSimilarArtists,
+		"async": c.readSimilarArtistsAsync,
+	} {
+
+		requests.Store(0)
+
+		similar, total, err := read(context.Background(), "Fugazi", 5, 0)
+		if err != nil {
+			t.Fatalf("read_similar_artists: %s: %v", name, err)
+		}
+
+		if len(similar) != 24 || total != 24 || requests.Load() != 3 {
+			t.Fatalf("read_similar_artists: %s: expected 24 of 24 artists in 3 requests, got %d of %d in %d", name, len(similar), total, requests.Load())
+		}
+	}
+
+	// the page without the total is read until the pages limit.
+	if _, total, err := c.readSimilarArtistsPage(context.Background(), "Fugazi", 2); err != nil || total != 0 {
+		t.Fatalf("read_similar_artists: expected no total, got %d, %v", total, err)
+	}
+}
+
+func TestParseSimilarArtistsCanceled(t *testing.T) {
+
+	data, err := os.ReadFile(filepath.Join("testdata", "similar_1.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, ok, err := parseSimilarArtists(context.Background(), data, similarLayouts[0])
+	if err != nil || !ok || len(page.similar) != 10 || page.total != 24 {
+		t.Fatalf("parse_similar_artists: expected 10 of 24 artists, got %+v, %t, %v", page, ok, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// the buffered page is not parsed after the cancel.
+	if _, _, err := parseSimilarArtists(ctx, data, similarLayouts[0]); !errors.Is(err, context.Canceled) {
+		t.Fatalf("parse_similar_artists: expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestSimilarArtistsFunc(t *testing.T) {
+
+	var requests atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		http.ServeFile(w, r, filepath.Join("testdata", fmt.Sprintf("similar_%d.html", page)))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	c := NewClient(WithBaseURL(srv.URL), WithHTTPClient(srv.Client()))
+
+	var names []string
+
+	if err := c.SimilarArtistsFunc(context.Background(), "Fugazi", 3, 0, func(artist string) error {
+		names = append(names, artist)
+		return nil
+	}); err != nil {
+		t.Fatalf("similar_artists_func: %v", err)
+	}
+
+	if len(names) != 24 || names[0] != "Unwound" {
+		t.Fatalf("similar_artists_func: expected 24 artists from %q, got %q", "Unwound", names)
+	}
+
+	// the callback error stops the read before the next page.
+	errStop := errors.New("stop")
+
+	requests.Store(0)
+
+	var n int
+
+	if err := c.SimilarArtistsFunc(context.Background(), "Fugazi", 3, 0, func(string) error {
+		if n++; n == 2 {
+			return errStop
+		}
+		return nil
+	}); !errors.Is(err, errStop) {
+		t.Fatalf("similar_artists_func: expected %v, got %v", errStop, err)
+	}
+
+	if n != 2 || requests.Load() != 1 {
+		t.Fatalf("similar_artists_func: expected 2 artists in 1 request, got %d in %d", n, requests.Load())
+	}
+}
+
+func TestReadSimilarArtistsZeroWorkers(t *testing.T) {
+
+	for _, workers := range []int{0, -1} {
+
+		cfg := DefaultConfig()
+		cfg.Workers = workers
+
+		c := newFixtureClient(t, map[string]string{
+			"/music/Fugazi/+similar?page=1": "similar_1.html",
+			"/music/Fugazi/+similar?page=2": "similar_2.html",
+		}, WithConfig(cfg))
+
+		if c.cfg.Workers != 1 {
+			t.Fatalf("new_client: %d workers: expected 1 worker, got %d", workers, c.cfg.Workers)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// the single worker reads the pages in order, same as the serial read.
+		artists, _, err := c.readSimilarArtistsAsync(ctx, "Fugazi", 2, 0)
+		if err != nil {
+			t.Fatalf("read_similar_artists_async: %d workers: %v", workers, err)
+		}
+
+		if len(artists) != 20 {
+			t.Fatalf("read_similar_artists_async: %d workers: expected 20 artists, got %d", workers, len(artists))
+		}
+	}
+}
+
+func TestReadOverviewSimilar(t *testing.T) {
+
+	fixtures := map[string]string{"/music/Fugazi": "overview.html"}
+
+	desc, err := newFixtureClient(t, fixtures).readOverview(context.Background(), "Fugazi")
+	if err != nil {
+		t.Fatalf("read_overview: %v", err)
+	}
+
+	if len(desc.SimilarArtists) != 0 {
+		t.Fatalf("read_overview: expected no similar artists, got %q", desc.SimilarArtists)
+	}
+
+	cfg := DefaultConfig()
+	cfg.OverviewSimilar = true
+
+	if desc, err = newFixtureClient(t, fixtures, WithConfig(cfg)).readOverview(context.Background(), "Fugazi"); err != nil {
+		t.Fatalf("read_overview: %v", err)
+	}
+
+	// the sidebar has 9 artists.
+	expected := []string{"Minor Threat", "Rites of Spring", "Unwound", "Shellac", "Jawbox", "Nation of Ulysses", "The Make-Up", "Slint"}
+
+	if !slices.Equal(desc.SimilarArtists, expected) {
+		t.Fatalf("read_overview: expected similar artists %q, got %q", expected, desc.SimilarArtists)
+	}
+}
+
+func TestReadTrend(t *testing.T) {
+
+	for _, tc := range []struct {
+		html  string
+		trend string
+		delta int
+	}{
+		{`<span class="header-metadata-tnew-trend header-metadata-tnew-trend--down" title="-310 listeners this week">-310</span>`, "down", -310},
+		{`<span class="header-metadata-tnew-trend">−1.5K</span>`, "down", -1500},
+		{`<span class="header-metadata-tnew-trend">+42</span>`, "up", 42},
+		{`<span class="header-metadata-tnew-trend" title="0 listeners this week"></span>`, "flat", 0},
+		{`<span class="header-metadata-tnew-trend header-metadata-tnew-trend--flat"></span>`, "flat", 0},
+	} {
+
+		tokenizer := html.NewTokenizer(strings.NewReader(tc.html))
+		tokenizer.Next()
+
+		_, iter := htmlq.MatchAttr(tokenizer, htmlq.TagAttr("span", "class", "header-metadata-tnew-trend"))
+
+		if trend, delta := readTrend(tokenizer, iter); trend != tc.trend || delta != tc.delta {
+			t.Errorf("read_trend: %s: expected %q, %d, got %q, %d", tc.html, tc.trend, tc.delta, trend, delta)
+		}
+	}
+}
+
+func TestReadTopAlbums(t *testing.T) {
+
+	for _, tc := range []struct {
+		sort     string
+		expected []Album
+	}{
+		{"", []Album{
+			{Title: "Repeater", Listeners: 312001, Image: "https://lastfm.freetls.fastly.net/i/u/300x300/repeater.jpg", Year: 1990},
+			{Title: "Unreleased"},
+			{Title: "13 Songs", Listeners: 287450, Year: 1989},
+			{Title: "First Demo", Year: 1988},
+		}},
+		{"year", []Album{
+			{Title: "First Demo", Year: 1988},
+			{Title: "13 Songs", Listeners: 287450, Year: 1989},
+			{Title: "Repeater", Listeners: 312001, Image: "https://lastfm.freetls.fastly.net/i/u/300x300/repeater.jpg", Year: 1990},
+			{Title: "Unreleased"},
+		}},
+	} {
+		t.Run("sort "+tc.sort, func(t *testing.T) {
+
+			cfg := DefaultConfig()
+			cfg.AlbumsSort = tc.sort
+
+			c := newFixtureClient(t, map[string]string{"/music/Fugazi/+albums?page=1": "albums.html"}, WithConfig(cfg))
+
+			albums, err := c.readTopAlbums(context.Background(), "Fugazi")
+			if err != nil {
+				t.Fatalf("read_top_albums: %v", err)
+			}
+
+			if len(albums) != len(tc.expected) {
+				t.Fatalf("read_top_albums: expected %d albums, got %d", len(tc.expected), len(albums))
+			}
+
+			for i, album := range tc.expected {
+				if !reflect.DeepEqual(*albums[i], album) {
+					t.Errorf("read_top_albums: %d: expected %+v, got %+v", i, album, *albums[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadTopTracks(t *testing.T) {
+
+	fixtures := map[string]string{
+		"/music/Fugazi/+tracks?page=1": "tracks.html",
+		"/music/Fugazi/+tracks?page=2": "similar_empty.html",
+	}
+
+	tracks := []Track{
+		{Title: "Waiting Room", Listeners: 512345, Duration: "2:53"},
+		{Title: "Merchandise", Listeners: 301002, Duration: "3:00"},
+		{Title: "Suggestion", Listeners: 198760},
+	}
+
+	for _, tc := range []struct {
+		name      string
+		pages     int
+		maxTracks int
+		expected  []Track
+	}{
+		{"all tracks", 1, 0, tracks},
+		{"stop on empty page", 3, 0, tracks},
+		{"max tracks", 1, 2, tracks[:2]},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			cfg := DefaultConfig()
+			cfg.TracksPages, cfg.MaxTracks = tc.pages, tc.maxTracks
+
+			c := newFixtureClient(t, fixtures, WithConfig(cfg))
+
+			tracks, err := c.readTopTracks(context.Background(), "Fugazi")
+			if err != nil {
+				t.Fatalf("read_top_tracks: %v", err)
+			}
+
+			if len(tracks) != len(tc.expected) {
+				t.Fatalf("read_top_tracks: expected %d tracks, got %d", len(tc.expected), len(tracks))
+			}
+
+			for i, track := range tc.expected {
+				if !reflect.DeepEqual(*tracks[i], track) {
+					t.Errorf("read_top_tracks: %d: expected %+v, got %+v", i, track, *tracks[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadTopAlbumsLinks(t *testing.T) {
+
+	cfg := DefaultConfig()
+	cfg.Links = true
+
+	c := newFixtureClient(t, map[string]string{"/music/Fugazi/+albums?page=1": "albums.html"}, WithConfig(cfg))
+
+	albums, err := c.readTopAlbums(context.Background(), "Fugazi")
+	if err != nil {
+		t.Fatalf("read_top_albums: %v", err)
+	}
+
+	expected := map[string]string{
+		"youtube": "https://www.youtube.com/watch?v=repeater",
+		"spotify": "https://open.spotify.com/album/repeater",
+		"amazon":  "https://www.amazon.co.uk/dp/repeater",
+	}
+
+	if len(albums) == 0 || !reflect.DeepEqual(albums[0].Links, expected) {
+		t.Fatalf("read_top_albums: expected links %v, got %+v", expected, albums)
+	}
+
+	if albums[1].Links != nil {
+		t.Fatalf("read_top_albums: expected no links, got %v", albums[1].Links)
+	}
+}
+
+func TestReadEventsLD(t *testing.T) {
+
+	c := newFixtureClient(t, map[string]string{"/music/Fugazi/+events": "events_ld.html"})
+
+	page, err := c.readEvents(context.Background(), "Fugazi")
+	if err != nil {
+		t.Fatalf("read_events: %v", err)
+	}
+
+	events := page.events
+
+	date := time.Date(2099, 6, 1, 20, 0, 0, 0, time.UTC)
+
+	expected := []*Event{{
+		Date:   &date,
+		Lineup: "Fugazi, Minor Threat",
+		Address: &EventAddress{
+			Name:     "9:30 Club",
+			Street:   "815 V St NW",
+			Locality: "Washington",
+			Code:     "20001",
+			Country:  "United States",
+			Lat:      38.9179,
+			Lon:      -77.0234,
+		},
+		Upcoming: true,
+	}}
+
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("read_events: expected %+v, got %+v", expected[0], events)
+	}
+}
+
+func TestReadEventsGeo(t *testing.T) {
+
+	c := newFixtureClient(t, map[string]string{"/music/Fugazi/+events": "events_list.html"})
+
+	page, err := c.readEvents(context.Background(), "Fugazi")
+	if err != nil {
+		t.Fatalf("read_events: %v", err)
+	}
+
+	events := page.events
+
+	if len(events) != 2 {
+		t.Fatalf("read_events: expected 2 events, got %d", len(events))
+	}
+
+	expected := []EventAddress{
+		{Name: "9:30 Club", Locality: "Washington", Country: "United States", Lat: 38.9179, Lon: -77.0234},
+		{Name: "Black Cat", Locality: "Washington", Country: "United States"},
+	}
+
+	for i, address := range expected {
+		if *events[i].Address != address {
+			t.Errorf("read_events: %d: expected %+v, got %+v", i, address, *events[i].Address)
+		}
+	}
+}
+
+func TestReadEventYears(t *testing.T) {
+
+	var requests atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.ServeFile(w, r, filepath.Join("testdata", "events.html"))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	c := NewClient(WithBaseURL(srv.URL), WithHTTPClient(srv.Client()))
+
+	page, err := c.readEvents(context.Background(), "Fugazi")
+	if err != nil {
+		t.Fatalf("read_events: %v", err)
+	}
+
+	if expected := []string{"Upcoming", "2003", "2002"}; !slices.Equal(page.years, expected) {
+		t.Fatalf("read_events: expected years %q, got %q", expected, page.years)
+	}
+
+	// the years and the events are read from the same page.
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("read_events: expected 1 request, got %d", n)
+	}
+}
+
+func TestParseEventsCanceled(t *testing.T) {
+
+	data, err := os.ReadFile(filepath.Join("testdata", "events.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := os.ReadFile(filepath.Join("testdata", "events_list.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient()
+
+	if years, err := parseEventYears(context.Background(), data); err != nil || len(years) == 0 {
+		t.Fatalf("parse_event_years: expected years, got %q, %v", years, err)
+	}
+
+	if events, err := c.parseEvents(context.Background(), list); err != nil || len(events) == 0 {
+		t.Fatalf("parse_events: expected events, got %d, %v", len(events), err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// the buffered page is not parsed after the cancel.
+	if _, err := parseEventYears(ctx, data); !errors.Is(err, context.Canceled) {
+		t.Fatalf("parse_event_years: expected %v, got %v", context.Canceled, err)
+	}
+
+	if _, err := c.parseEvents(ctx, list); !errors.Is(err, context.Canceled) {
+		t.Fatalf("parse_events: expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestReadWikiCompact(t *testing.T) {
+
+	cfg := DefaultConfig()
+	cfg.WikiCompact = true
+
+	wiki, err := newFixtureClient(t, map[string]string{"/music/Fugazi/+wiki": "wiki.html"}, WithConfig(cfg)).readWiki(context.Background(), "Fugazi")
+	if err != nil {
+		t.Fatalf("read_wiki: %v", err)
+	}
+
+	if strings.ContainsAny(wiki.BioCompact, "\n\t") || strings.Contains(wiki.BioCompact, "  ") {
+		t.Fatalf("read_wiki: expected compact bio, got %q", wiki.BioCompact)
+	}
+
+	if expected := "They are noted for their style-transcending music. And for their DIY ethical stance."; !strings.Contains(wiki.BioCompact, expected) {
+		t.Fatalf("read_wiki: expected %q in compact bio %q", expected, wiki.BioCompact)
+	}
+
+	// the structured bio is kept.
+	if !slices.Contains(wiki.Bio, "And for their DIY ethical stance.") {
+		t.Fatalf("read_wiki: unexpected bio %q", wiki.Bio)
+	}
+}
+
+func TestReadWikiAttribution(t *testing.T) {
+
+	wiki, err := newFixtureClient(t, map[string]string{"/music/Fugazi/+wiki": "wiki_attribution.html"}).readWiki(context.Background(), "Fugazi")
+	if err != nil {
+		t.Fatalf("read_wiki: %v", err)
+	}
+
+	if wiki.Author != "dischord" {
+		t.Errorf("read_wiki: expected author %q, got %q", "dischord", wiki.Author)
+	}
+
+	if wiki.Published == nil || wiki.Published.Format(time.DateOnly) != "2020-03-12" {
+		t.Errorf("read_wiki: expected published 2020-03-12, got %v", wiki.Published)
+	}
+
+	bio := []string{
+		`Fugazi is an American post-hardcore band that formed in "Washington, D.C.", in 1986.`,
+		"Their songs were written by the whole band.",
+	}
+
+	if !slices.Equal(wiki.Bio, bio) {
+		t.Errorf("read_wiki: expected bio %q, got %q", bio, wiki.Bio)
+	}
+
+	// the user link is not the reference.
+	if len(wiki.Refs) != 1 || wiki.Refs[0].Name != "Washington, D.C." {
+		t.Errorf("read_wiki: unexpected refs %+v", wiki.Refs)
+	}
+}
+
+func TestReadWikiRefFormat(t *testing.T) {
+
+	fixtures := map[string]string{"/music/Fugazi/+wiki": "wiki.html"}
+
+	for _, refFormat := range []string{"%q", "[%s]"} {
+		t.Run(refFormat, func(t *testing.T) {
+
+			t.Parallel()
+
+			cfg := DefaultConfig()
+			cfg.RefFormat = refFormat
+
+			wiki, err := newFixtureClient(t, fixtures, WithConfig(cfg)).readWiki(context.Background(), "Fugazi")
+			if err != nil {
+				t.Fatalf("read_wiki: %v", err)
+			}
+
+			if ref := fmt.Sprintf(refFormat, "Ian MacKaye"); !strings.Contains(strings.Join(wiki.Bio, "\n"), ref) {
+				t.Fatalf("read_wiki: expected %s in bio %q", ref, wiki.Bio)
+			}
+		})
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+
+	task := withTimeout(10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if err := task(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("with_timeout: expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	noTimeout := withTimeout(0, func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); ok {
+			return errors.New("unexpected deadline")
+		}
+		return nil
+	})
+
+	if err := noTimeout(context.Background()); err != nil {
+		t.Fatalf("with_timeout: %v", err)
+	}
+}
+
+func TestRetryOnEmpty(t *testing.T) {
+
+	defer func(d time.Duration) { retryOnEmptyDelay = d }(retryOnEmptyDelay)
+	retryOnEmptyDelay = time.Millisecond
+
+	for _, tc := range []struct {
+		name     string
+		enabled  bool
+		results  []int
+		expected int
+	}{
+		{"disabled", false, []int{0, 5}, 1},
+		{"empty then full", true, []int{0, 5}, 2},
+		{"not empty", true, []int{5, 5}, 1},
+		{"single retry", true, []int{0, 0, 5}, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			c := NewClient(WithConfig(Config{RetryOnEmpty: tc.enabled}))
+
+			var reads int
+
+			err := c.retryOnEmpty(context.Background(), "test", func(context.Context) (int, error) {
+				reads++
+				return tc.results[reads-1], nil
+			})
+			if err != nil {
+				t.Fatalf("retry_on_empty: %v", err)
+			}
+
+			if reads != tc.expected {
+				t.Fatalf("retry_on_empty: expected %d reads, got %d", tc.expected, reads)
+			}
+		})
+	}
+}
+
+func TestRetrySection(t *testing.T) {
+
+	defer func(d time.Duration) { sectionRetryDelay = d }(sectionRetryDelay)
+	sectionRetryDelay = time.Millisecond
+
+	var (
+		full    = []*Album{{Title: "Repeater"}}
+		failure = errors.New("tokenizer: unexpected EOF")
+		missing = &StatusError{Code: http.StatusNotFound}
+	)
+
+	type result struct {
+		albums []*Album
+		err    error
+	}
+
+	for _, tc := range []struct {
+		name     string
+		retries  int
+		results  []result
+		expected int
+	}{
+		{"disabled", 0, []result{{nil, failure}, {full, nil}}, 1},
+		{"error then full", 2, []result{{nil, failure}, {full, nil}}, 2},
+		{"empty then full", 2, []result{{nil, nil}, {full, nil}}, 2},
+		{"not empty", 2, []result{{full, nil}}, 1},
+		{"capped", 2, []result{{nil, failure}, {nil, failure}, {nil, failure}, {full, nil}}, 3},
+		{"not found", 2, []result{{nil, missing}, {full, nil}}, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			c := NewClient(WithConfig(Config{SectionRetries: tc.retries}))
+
+			var reads int
+
+			_, err := c.retrySection(context.Background(), "albums", func(context.Context) (any, error) {
+				reads++
+				return tc.results[reads-1].albums, tc.results[reads-1].err
+			})
+
+			if reads != tc.expected {
+				t.Fatalf("retry_section: expected %d reads, got %d", tc.expected, reads)
+			}
+
+			if expected := tc.results[reads-1].err; err != expected {
+				t.Fatalf("retry_section: expected %v, got %v", expected, err)
+			}
+		})
+	}
+}
+
+func TestReadBandBestEffort(t *testing.T) {
+
+	fixtures := map[string]string{
+		"/music/Fugazi":              "overview.html",
+		"/music/Fugazi/+tags?page=1": "tags.html",
+	}
+
+	with := sections{"wiki": true, "tags": true}
+
+	if _, err := newFixtureClient(t, fixtures).readBand(context.Background(), "Fugazi", with); err == nil {
+		t.Fatalf("read_band: expected wiki error")
+	}
+
+	cfg := DefaultConfig()
+	cfg.BestEffort = true
+
+	desc, err := newFixtureClient(t, fixtures, WithConfig(cfg)).readBand(context.Background(), "Fugazi", with)
+	if err != nil {
+		t.Fatalf("read_band: %v", err)
+	}
+
+	if len(desc.Tags) == 0 || desc.Wiki != nil {
+		t.Fatalf("read_band: expected tags without wiki, got %+v", desc)
+	}
+
+	if len(desc.Warnings) != 1 || !strings.HasPrefix(desc.Warnings[0], "read_wiki:") {
+		t.Fatalf("read_band: expected wiki warning, got %q", desc.Warnings)
+	}
+
+	if _, err := newFixtureClient(t, nil, WithConfig(cfg)).readBand(context.Background(), "Fugazi", with); !errors.Is(err, ErrBandNotFound) {
+		t.Fatalf("read_band: expected %v, got %v", ErrBandNotFound, err)
+	}
+}
+
+func TestReadBandStats(t *testing.T) {
+
+	c := newFixtureClient(t, map[string]string{"/music/Fugazi": "overview.html"})
+
+	before := stats.bands.Load()
+
+	if _, err := c.readBand(context.Background(), "Fugazi", sections{}); err != nil {
+		t.Fatalf("read_band: %v", err)
+	}
+
+	if n := stats.bands.Load() - before; n != 1 {
+		t.Fatalf("read_band: expected 1 band in stats, got %d", n)
+	}
+}
+
+func TestReadBandWhitespace(t *testing.T) {
+
+	c := newFixtureClient(t, map[string]string{
+		"/music/Fugazi":                 "overview.html",
+		"/music/Fugazi/+wiki":           "wiki.html",
+		"/music/Fugazi/+tags?page=1":    "tags.html",
+		"/music/Fugazi/+similar?page=1": "similar_3.html",
+		"/music/Fugazi/+events":         "events.html",
+	})
+
+	c.cfg.SimilarArtistsPages = 1
+
+	desc, err := c.readBand(context.Background(), "Fugazi", sections{"wiki": true, "tags": true, "related-tags": true, "similar-artists": true, "events": true})
+	if err != nil {
+		t.Fatalf("read_band: %v", err)
+	}
+
+	b, err := json.Marshal(desc)
+	if err != nil {
+		t.Fatalf("json_marshal: %v", err)
+	}
+
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatalf("json_unmarshal: %v", err)
+	}
+
+	var walk func(path string, v any)
+
+	walk = func(path string, v any) {
+		switch v := v.(type) {
+		case string:
+			if v != strings.Join(strings.Fields(v), " ") {
+				t.Errorf("read_band: %s: unnormalized whitespace in %q", path, v)
+			}
+		case []any:
+			for i, v := range v {
+				walk(fmt.Sprintf("%s[%d]", path, i), v)
+			}
+		case map[string]any:
+			for k, v := range v {
+				walk(path+"."+k, v)
+				walk(path+"."+k+" (key)", k)
+			}
+		}
+	}
+
+	walk("band", v)
+
+	// the line breaks in the bio are kept.
+	if !slices.Contains(desc.Wiki.Bio, "They are noted for their style-transcending music.") ||
+		!slices.Contains(desc.Wiki.Bio, "And for their DIY ethical stance.") {
+		t.Errorf("read_band: expected bio line break, got %q", desc.Wiki.Bio)
+	}
+}
+
+func TestReadSearch(t *testing.T) {
+
+	fixtures := map[string]string{
+		"/search/artists?q=fugazi": "search.html",
+		"/search/artists?q=xyzzy":  "search_empty.html",
+	}
+
+	c := newFixtureClient(t, fixtures)
+
+	names, err := c.readSearch(context.Background(), "fugazi")
+	if err != nil {
+		t.Fatalf("read_search: %v", err)
+	}
+
+	if expected := []string{"Fugazi", "Fugazi Tribute"}; !slices.Equal(names, expected) {
+		t.Fatalf("read_search: expected %q, got %q", expected, names)
+	}
+
+	if names, err = c.readSearch(context.Background(), "xyzzy"); err != nil || len(names) != 0 {
+		t.Fatalf("read_search: expected no names, got %q, %v", names, err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Resolve = true
+
+	c = newFixtureClient(t, fixtures, WithConfig(cfg))
+
+	if name, err := c.resolveBand(context.Background(), "fugazi"); err != nil || name != "Fugazi" {
+		t.Fatalf("resolve_band: expected %q, got %q, %v", "Fugazi", name, err)
+	}
+
+	if _, err := c.resolveBand(context.Background(), "xyzzy"); !errors.Is(err, ErrBandNotFound) {
+		t.Fatalf("resolve_band: expected %v, got %v", ErrBandNotFound, err)
+	}
+}
+
+func TestResolveBandURL(t *testing.T) {
+
+	c := NewClient()
+
+	for _, tc := range []struct {
+		arg, expected string
+	}{
+		{"https://www.last.fm/music/Fugazi", "Fugazi"},
+		{"https://www.last.fm/music/Minor+Threat/+wiki?from=search#bio", "Minor+Threat"},
+		{"https://last.fm/music/Sigur+R%C3%B3s/", "Sigur+R%C3%B3s"},
+		{"https://www.last.fm/de/music/Fugazi", ""},
+		{"https://example.com/music/Fugazi", ""},
+		{"https://www.last.fm/music/+", ""},
+		{"https://www.last.fm/music/%zz", ""},
+	} {
+		slug, err := c.resolveBand(context.Background(), tc.arg)
+		if tc.expected == "" {
+			if err == nil {
+				t.Errorf("resolve: %s: expected error, got %q", tc.arg, slug)
+			}
+			continue
+		}
+		if err != nil || slug != tc.expected {
+			t.Errorf("resolve: %s: expected %q, got %q, %v", tc.arg, tc.expected, slug, err)
+		}
+	}
+}
+
+func TestResolveMBID(t *testing.T) {
+
+	const mbid = "7f625ba3-2f6a-4a3e-9db2-8f2b42466a07"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mbid/"+mbid, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/music/Fugazi", http.StatusFound)
+	})
+	mux.HandleFunc("/music/Fugazi", func(w http.ResponseWriter, r *http.Request) {})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := NewClient(WithBaseURL(srv.URL), WithHTTPClient(srv.Client()))
+
+	slug, err := c.resolveMBID(context.Background(), strings.ToUpper(mbid))
+	if err != nil || slug != "Fugazi" {
+		t.Fatalf("resolve_mbid: expected %q, got %q, %v", "Fugazi", slug, err)
+	}
+
+	if _, err := c.resolveMBID(context.Background(), "00000000-0000-0000-0000-000000000000"); !errors.Is(err, ErrBandNotFound) {
+		t.Fatalf("resolve_mbid: expected %v, got %v", ErrBandNotFound, err)
+	}
+
+	if _, err := c.resolveMBID(context.Background(), "fugazi"); err == nil {
+		t.Fatalf("resolve_mbid: expected invalid mbid error")
+	}
+}
+
+func TestReadChartArtists(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/charts" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, "testdata/charts.html")
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithBaseURL(srv.URL), WithHTTPClient(srv.Client()))
+
+	slugs, err := c.readChartArtists(context.Background())
+	if err != nil {
+		t.Fatalf("read_charts: %v", err)
+	}
+
+	if expected := []string{"Fugazi", "Sigur+R%C3%B3s", "Minor+Threat"}; !slices.Equal(slugs, expected) {
+		t.Fatalf("read_charts: expected %q, got %q", expected, slugs)
+	}
+
+	slug, err := c.readRandomArtist(context.Background())
+	if err != nil || !slices.Contains(slugs, slug) {
+		t.Fatalf("random: expected one of %q, got %q, %v", slugs, slug, err)
+	}
+}
+
+func TestReadBandSources(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.RequestURI() {
+		case "/music/fugazi":
+			// the band name is redirected to the canonical one.
+			http.Redirect(w, r, "/music/Fugazi", http.StatusMovedPermanently)
+		case "/music/Fugazi":
+			http.ServeFile(w, r, "testdata/overview.html")
+		case "/music/fugazi/+tags?page=1":
+			http.ServeFile(w, r, "testdata/tags.html")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.IncludeSources = true
+
+	c := NewClient(WithBaseURL(srv.URL), WithHTTPClient(srv.Client()), WithConfig(cfg))
+
+	desc, err := c.readBand(context.Background(), "fugazi", sections{"tags": true})
+	if err != nil {
+		t.Fatalf("read_band: %v", err)
+	}
+
+	expected := bandSources{
+		"overview": {srv.URL + "/music/Fugazi"},
+		"tags":     {srv.URL + "/music/fugazi/+tags?page=1"},
+	}
+
+	if !reflect.DeepEqual(desc.Sources, expected) {
+		t.Fatalf("read_band: expected sources %v, got %v", expected, desc.Sources)
+	}
+
+	if desc, err = NewClient(WithBaseURL(srv.URL), WithHTTPClient(srv.Client())).readBand(context.Background(), "fugazi", sections{"tags": true}); err != nil || desc.Sources != nil {
+		t.Fatalf("read_band: expected no sources, got %v, %v", desc, err)
+	}
+}
+
+func TestParseAbbr(t *testing.T) {
+
+	for s, expected := range map[string]int{
+		"18,386,097":          18386097,
+		"4,532,198 listeners": 4532198,
+		"4.5M":                4500000,
+		"751.7K":              751700,
+		"1.2B scrobbles":      1200000000,
+		"12":                  12,
+		"":                    0,
+		"n/a":                 0,
+	} {
+		if n := parseAbbr(s); n != expected {
+			t.Errorf("parse_abbr: %q: expected %d, got %d", s, expected, n)
+		}
+	}
+}
+
+func TestSplitLocation(t *testing.T) {
+
+	for _, tc := range []struct {
+		location, city, country string
+	}{
+		{"Reykjavík, Iceland", "Reykjavík", "Iceland"},
+		{"Washington, District of Columbia, United States", "Washington, District of Columbia", "United States"},
+		{"Iceland", "", "Iceland"},
+		{" London ,  United Kingdom ", "London", "United Kingdom"},
+	} {
+		if city, country := splitLocation(tc.location); city != tc.city || country != tc.country {
+			t.Errorf("split_location: %q: expected %q, %q, got %q, %q", tc.location, tc.city, tc.country, city, country)
+		}
+	}
+}
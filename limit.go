@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// limitTransport caps the number of simultaneous requests to last.fm regardless of
+// the number of workers. The slot is held until the response body is closed.
+type limitTransport struct {
+	http.RoundTripper
+	sem chan struct{}
+}
+
+func newLimitTransport(transport http.RoundTripper, maxConns int) *limitTransport {
+	return &limitTransport{transport, make(chan struct{}, maxConns)}
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	select {
+	case t.sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err != nil {
+		<-t.sem
+		return nil, err
+	}
+
+	resp.Body = &limitBody{ReadCloser: resp.Body, release: func() { <-t.sem }}
+
+	return resp, nil
+}
+
+type limitBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *limitBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// setupLogs function switches the logs to the JSON lines on stderr (-log-json). The log
+// package output, e.g. the resolve and the cache messages, goes through the same handler.
+func setupLogs() {
+	if logJSON {
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+	}
+}
+
+type logKey struct{}
+
+// logFields is the context value of the band and the section the requests belong to.
+type logFields struct {
+	band, section string
+}
+
+// withLogFields function returns the context with the band and the section for the
+// structured request logs.
+func withLogFields(ctx context.Context, band, section string) context.Context {
+	return context.WithValue(ctx, logKey{}, &logFields{band, section})
+}
+
+// logged function returns the task with the band and the section for the request logs.
+func logged(band, section string, task func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return task(withLogFields(ctx, band, section))
+	}
+}
+
+// logAttrs function returns the band and the section log attributes of the context.
+func logAttrs(ctx context.Context) []any {
+
+	fields, ok := ctx.Value(logKey{}).(*logFields)
+	if !ok {
+		return nil
+	}
+
+	return []any{"band", fields.band, "section", fields.section}
+}
+
+// logRequest function logs the request url, the response status or the error and the
+// round-trip duration (-verbose).
+func logRequest(req *http.Request, resp *http.Response, err error, d time.Duration) {
+
+	if !logJSON {
+		if err != nil {
+			log.Printf("%s %s: %v (%v)", req.Method, req.URL, err, d)
+		} else {
+			log.Printf("%s %s: %s (%v)", req.Method, req.URL, resp.Status, d)
+		}
+		return
+	}
+
+	args := append([]any{"method", req.Method, "url", req.URL.String()}, logAttrs(req.Context())...)
+
+	if err != nil {
+		slog.Error("request", append(args, "error", err.Error(), "duration_ms", d.Milliseconds())...)
+		return
+	}
+
+	slog.Info("request", append(args, "status", resp.StatusCode, "duration_ms", d.Milliseconds())...)
+}
+
+// logWarning function logs the failed section of the band (-best-effort).
+func logWarning(band string, err error) {
+
+	if !logJSON {
+		log.Printf("warning: %s: %v", band, err)
+		return
+	}
+
+	slog.Warn("section failed", "band", band, "error", err.Error())
+}
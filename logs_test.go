@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLogRequestJSON(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	defer func(logger *slog.Logger, v bool) { slog.SetDefault(logger); logJSON = v }(slog.Default(), logJSON)
+
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	logJSON = true
+
+	ctx := withLogFields(context.Background(), "Fugazi", "tags")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://www.last.fm/music/Fugazi/+tags", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logRequest(req, &http.Response{StatusCode: http.StatusOK, Status: "200 OK"}, nil, 1500*time.Millisecond)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("log: %v: %s", err, buf.String())
+	}
+
+	for key, expected := range map[string]any{
+		"msg":         "request",
+		"url":         "https://www.last.fm/music/Fugazi/+tags",
+		"status":      float64(http.StatusOK),
+		"duration_ms": float64(1500),
+		"band":        "Fugazi",
+		"section":     "tags",
+	} {
+		if record[key] != expected {
+			t.Errorf("%s: expected %v, got %v", key, expected, record[key])
+		}
+	}
+}
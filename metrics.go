@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	upstreamRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "lastfmq_upstream_requests_total",
+		Help: "The total number of requests to last.fm by response status code.",
+	}, []string{"code"})
+
+	upstreamDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "lastfmq_upstream_request_duration_seconds",
+		Help:    "The last.fm request round-trip duration.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "lastfmq_cache_requests_total",
+		Help: "The total number of page reads from the -cache-dir by result, hit or miss.",
+	}, []string{"result"})
+)
+
+var registerOnce sync.Once
+
+// registerMetrics function registers the metrics once. It is called in server mode only,
+// the default client is instrumented by parseFlags then.
+func registerMetrics() {
+	registerOnce.Do(func() {
+		prometheus.MustRegister(upstreamRequests, upstreamDuration, cacheRequests)
+	})
+}
+
+// metricsTransport records the request count and the round-trip duration. It is placed
+// right above the limit transport, so the cached pages and the retries are not counted
+// as the last.fm requests, and each retry is counted on its own.
+type metricsTransport struct {
+	http.RoundTripper
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	start := time.Now()
+
+	resp, err := t.RoundTripper.RoundTrip(req)
+
+	upstreamDuration.Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		upstreamRequests.WithLabelValues("error").Inc()
+		return nil, err
+	}
+
+	upstreamRequests.WithLabelValues(strconv.Itoa(resp.StatusCode)).Inc()
+
+	return resp, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+)
+
+// maxRecordSize is the maximum size of the ndjson record line.
+const maxRecordSize = 16 << 20
+
+// readNDJSON function reads the band records previously written in the json format,
+// one per line, and calls the function for each record. The empty lines are skipped,
+// the -batch records of the failed bands are logged to stderr and skipped as well.
+func readNDJSON(r io.Reader, fn func(*bandDesc) error) error {
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxRecordSize)
+
+	for line := 1; scanner.Scan(); line++ {
+
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+
+		var failed batchError
+		if json.Unmarshal(b, &failed) == nil && failed.Error != "" {
+			log.Printf("read_ndjson: line %d: %s: %s", line, failed.BandName, failed.Error)
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+
+		desc := new(bandDesc)
+
+		if err := dec.Decode(desc); err != nil {
+			return fmt.Errorf("read_ndjson: line %d: %w", line, err)
+		}
+
+		if dec.More() {
+			return fmt.Errorf("read_ndjson: line %d: unexpected data after the record", line)
+		}
+
+		if err := fn(desc); err != nil {
+			return fmt.Errorf("read_ndjson: line %d: %w", line, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read_ndjson: %w", err)
+	}
+
+	return nil
+}
+
+// fromNDJSON function re-renders the saved band records with the configured format and
+// fields, or saves them into the sqlite database, without fetching.
+func (c *Client) fromNDJSON(ctx context.Context, r io.Reader, w io.Writer, with sections) error {
+	return readNDJSON(r, func(desc *bandDesc) error {
+
+		if c.cfg.SQLite != nil {
+			return c.cfg.SQLite.save(ctx, desc)
+		}
+
+		out, err := c.renderBand(desc, with)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(out)
+		return err
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadNDJSONRoundTrip(t *testing.T) {
+
+	descs := []*bandDesc{
+		{BandName: "Fugazi", Listeners: 751721, Tags: []string{"post-hardcore"}},
+		{BandName: "Minor Threat", Wiki: &Wiki{Members: []*Member{{Name: "Ian MacKaye"}}}},
+	}
+
+	var b strings.Builder
+
+	c := NewClient()
+
+	for _, desc := range descs {
+		out, err := c.renderBand(desc, sections{})
+		if err != nil {
+			t.Fatalf("render_band: %v", err)
+		}
+		b.Write(out)
+		b.WriteString("\n") // empty lines are skipped.
+	}
+
+	var read []*bandDesc
+
+	if err := readNDJSON(strings.NewReader(b.String()), func(desc *bandDesc) error {
+		read = append(read, desc)
+		return nil
+	}); err != nil {
+		t.Fatalf("read_ndjson: %v", err)
+	}
+
+	if !reflect.DeepEqual(read, descs) {
+		t.Fatalf("read_ndjson: expected %+v, got %+v", descs, read)
+	}
+}
+
+func TestReadNDJSONBatch(t *testing.T) {
+
+	cfg := DefaultConfig()
+	cfg.Ordered = true
+
+	c := newFixtureClient(t, map[string]string{"/music/Fugazi": "overview.html"}, WithConfig(cfg))
+
+	var b strings.Builder
+
+	if err := c.runBatch(context.Background(), []string{"Fugazi", "Nobody", "Fugazi"}, &b, sections{}); err == nil {
+		t.Fatalf("run_batch: expected failure for the missing band")
+	}
+
+	var names []string
+
+	// the failed band record is skipped.
+	if err := readNDJSON(strings.NewReader(b.String()), func(desc *bandDesc) error {
+		names = append(names, desc.BandName)
+		return nil
+	}); err != nil {
+		t.Fatalf("read_ndjson: %v", err)
+	}
+
+	if expected := []string{"Fugazi", "Fugazi"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("read_ndjson: expected %q, got %q", expected, names)
+	}
+}
+
+func TestReadNDJSONMalformed(t *testing.T) {
+
+	for _, tc := range []struct {
+		name, input, line string
+	}{
+		{"syntax", "{\"band_name\":\"Fugazi\"}\n{\"band_name\":\n", "line 2"},
+		{"unknown field", "\n\n{\"band\":\"Fugazi\"}\n", "line 3"},
+		{"trailing data", "{\"band_name\":\"Fugazi\"} {}\n", "line 1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			err := readNDJSON(strings.NewReader(tc.input), func(*bandDesc) error { return nil })
+			if err == nil || !strings.Contains(err.Error(), tc.line) {
+				t.Fatalf("read_ndjson: expected error at %s, got %v", tc.line, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// bandDescFields function returns the json field names of the band description.
+func bandDescFields() []string {
+
+	var (
+		typ = reflect.TypeOf(bandDesc{})
+		ret = make([]string, 0, typ.NumField())
+	)
+
+	for i := 0; i < typ.NumField(); i++ {
+		if name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ","); name != "" && name != "-" {
+			ret = append(ret, name)
+		}
+	}
+
+	return ret
+}
+
+// fieldsFlag function parses the comma-separated list of the band description fields.
+func fieldsFlag(fields *[]string) func(string) error {
+	return func(s string) error {
+
+		valid := bandDescFields()
+
+		for _, field := range strings.Split(s, ",") {
+			if field = strings.TrimSpace(field); field == "" {
+				continue
+			}
+			if !slices.Contains(valid, field) {
+				return fmt.Errorf("unknown field %q, valid fields are: %s", field, strings.Join(valid, ","))
+			}
+			*fields = append(*fields, field)
+		}
+
+		return nil
+	}
+}
+
+// emptyFieldsType is the band description type without omitempty, so all the fields
+// are always present in json. The types are convertible as differ in the tags only.
+var emptyFieldsType = func() reflect.Type {
+
+	var (
+		typ    = reflect.TypeOf(bandDesc{})
+		fields = make([]reflect.StructField, typ.NumField())
+	)
+
+	for i := range fields {
+		fields[i] = typ.Field(i)
+		name, _, _ := strings.Cut(fields[i].Tag.Get("json"), ",")
+		fields[i].Tag = reflect.StructTag(fmt.Sprintf("json:%q", name))
+	}
+
+	return reflect.StructOf(fields)
+}()
+
+// withEmptyFields function returns the band description with all the fields present
+// in json (-include-empty).
+func withEmptyFields(desc *bandDesc) any {
+	return reflect.ValueOf(desc).Elem().Convert(emptyFieldsType).Interface()
+}
+
+// selectFields function returns the band description with only the selected fields.
+func selectFields(desc any, fields []string) (map[string]any, error) {
+
+	b, err := json.Marshal(desc)
+	if err != nil {
+		return nil, fmt.Errorf("select_fields: marshal: %w", err)
+	}
+
+	all := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, fmt.Errorf("select_fields: unmarshal: %w", err)
+	}
+
+	ret := make(map[string]any, len(fields))
+
+	for _, field := range fields {
+		if val, ok := all[field]; ok {
+			ret[field] = val
+		}
+	}
+
+	return ret, nil
+}
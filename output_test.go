@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestWithEmptyFields(t *testing.T) {
+
+	desc := &bandDesc{BandName: "Fugazi", Tags: []string{"post-hardcore"}}
+
+	b, err := json.Marshal(withEmptyFields(desc))
+	if err != nil {
+		t.Fatalf("json_marshal: %v", err)
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(b, &record); err != nil {
+		t.Fatalf("json_unmarshal: %v", err)
+	}
+
+	for _, field := range bandDescFields() {
+		if _, ok := record[field]; !ok {
+			t.Errorf("with_empty_fields: missing field %q", field)
+		}
+	}
+
+	if record["band_name"] != "Fugazi" || record["scrobbles"] != 0.0 {
+		t.Errorf("with_empty_fields: unexpected record %v", record)
+	}
+
+	// the selected fields are present even if empty.
+	selected, err := selectFields(withEmptyFields(desc), []string{"band_name", "listeners"})
+	if err != nil {
+		t.Fatalf("select_fields: %v", err)
+	}
+
+	keys := make([]string, 0, len(selected))
+	for key := range selected {
+		keys = append(keys, key)
+	}
+
+	if slices.Sort(keys); !slices.Equal(keys, []string{"band_name", "listeners"}) {
+		t.Errorf("select_fields: expected band_name and listeners, got %v", keys)
+	}
+}
+
+func TestBareSection(t *testing.T) {
+
+	desc := &bandDesc{
+		BandName:       "Fugazi",
+		SimilarArtists: []string{"Minor Threat"},
+		TopAlbums:      []*Album{{Title: "Repeater"}},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		with     sections
+		expected string
+	}{
+		{"similar artists", sections{"similar-artists": true}, `["Minor Threat"]`},
+		{"albums", sections{"albums": true}, `[{"title":"Repeater"}]`},
+		{"empty tags", sections{"tags": true}, `[]`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			b, err := json.Marshal(bareSection(desc, tc.with))
+			if err != nil {
+				t.Fatalf("json_marshal: %v", err)
+			}
+
+			if string(b) != tc.expected {
+				t.Fatalf("bare_section: expected %s, got %s", tc.expected, b)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// pageFetcher reads the items of the section page.
+type pageFetcher[T any] func(ctx context.Context, pageNum int) ([]T, error)
+
+// fetchPagesConcurrent function reads the pages from offset+1 to offset+pages with the
+// workers and returns the items in the page order. The pages can arrive in any order
+// and have any number of items, the empty pages leave no gaps in the result. The first
+// error stops the workers and is returned. Each page read is logged if verbose is set.
+func fetchPagesConcurrent[T any](ctx context.Context, fetch pageFetcher[T], pages, offset, workers int, verbose bool) ([]T, error) {
+
+	type outValue struct {
+		page  int
+		items []T
+	}
+
+	pageCount, outC, errC, wg := new(atomic.Int32), make(chan outValue), make(chan error, 1), new(sync.WaitGroup)
+	defer close(outC)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// start from the page offset same as synchronous version.
+	pageCount.Store(int32(offset))
+
+	for i := 0; i < max(1, min(workers, pages)); i++ {
+
+		wg.Add(1)
+
+		go func(ctx context.Context, worker int) {
+
+			defer wg.Done()
+
+			for pageNum := int(pageCount.Add(1)); pageNum <= pages+offset; pageNum = int(pageCount.Add(1)) {
+
+				items, err := fetch(ctx, pageNum)
+				if verbose {
+					log.Printf("fetch_pages: worker %d: page %d: %d items", worker, pageNum, len(items))
+				}
+				if err != nil {
+					select {
+					case errC <- err:
+						cancel() // stop other workers.
+					default:
+						// the first error is already reported.
+					}
+					return
+				}
+
+				// empty page doesn't stop the worker, the later pages may still have items.
+				if len(items) == 0 {
+					continue
+				}
+
+				outC <- outValue{pageNum, items}
+			}
+
+		}(ctx, i)
+	}
+
+	doneC := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+		close(doneC)
+	}()
+
+	var byPage = make(map[int][]T, pages)
+
+loop:
+	for {
+		select {
+		case <-doneC:
+			break loop // all goroutines terminated.
+		case val := <-outC:
+			byPage[val.page] = val.items
+		}
+	}
+
+	select {
+	case err := <-errC:
+		return nil, err
+	default:
+	}
+
+	ret := make([]T, 0, pageSize*pages)
+
+	for i := 1 + offset; i <= pages+offset; i++ {
+		ret = append(ret, byPage[i]...)
+	}
+
+	return ret, nil
+}
+
+// readPages function reads the section pages until the first empty page, serially or
+// concurrently with the -pages-concurrent workers. The maximum number of items is not
+// limited if zero.
+func readPages[T any](ctx context.Context, c *Client, fetch pageFetcher[T], pages, maxItems int) ([]T, error) {
+
+	ret := []T{}
+
+	if c.cfg.PagesConcurrent > 1 {
+
+		// each page is read as a single item to keep the empty pages, so the pages
+		// end the same way as with the serial read.
+		byPage, err := fetchPagesConcurrent(ctx, func(ctx context.Context, pageNum int) ([][]T, error) {
+			items, err := fetch(ctx, pageNum)
+			if err != nil {
+				return nil, err
+			}
+			return [][]T{items}, nil
+		}, pages, 0, c.cfg.PagesConcurrent, c.cfg.Verbose)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, items := range byPage {
+			if len(items) == 0 {
+				break
+			}
+			ret = append(ret, items...)
+		}
+
+	} else {
+
+		for i := 1; i <= pages && (maxItems <= 0 || len(ret) < maxItems); i++ {
+
+			items, err := fetch(ctx, i)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(items) == 0 {
+				break
+			}
+
+			ret = append(ret, items...)
+		}
+	}
+
+	if maxItems > 0 && len(ret) > maxItems {
+		ret = ret[:maxItems]
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testPages function returns the page fetcher with the page numbers as the items,
+// the pages are delayed randomly to shuffle the workers.
+func testPages(empty ...int) pageFetcher[int] {
+	return func(ctx context.Context, pageNum int) ([]int, error) {
+		time.Sleep(time.Duration(rand.IntN(500)) * time.Microsecond)
+		if slices.Contains(empty, pageNum) {
+			return nil, nil
+		}
+		return []int{pageNum, pageNum}, nil
+	}
+}
+
+func TestFetchPagesConcurrent(t *testing.T) {
+
+	for _, tc := range []struct {
+		name                   string
+		fetch                  pageFetcher[int]
+		pages, offset, workers int
+		expected               []int
+	}{
+		{"single worker", testPages(), 3, 0, 1, []int{1, 1, 2, 2, 3, 3}},
+		{"zero workers", testPages(), 3, 0, 0, []int{1, 1, 2, 2, 3, 3}},
+		{"negative workers", testPages(), 3, 0, -1, []int{1, 1, 2, 2, 3, 3}},
+		{"more workers than pages", testPages(), 2, 0, 8, []int{1, 1, 2, 2}},
+		{"offset", testPages(), 3, 2, 4, []int{3, 3, 4, 4, 5, 5}},
+		{"empty pages", testPages(1, 3), 4, 0, 4, []int{2, 2, 4, 4}},
+		{"all empty", testPages(1, 2), 2, 0, 2, []int{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+
+				items, err := fetchPagesConcurrent(context.Background(), tc.fetch, tc.pages, tc.offset, tc.workers, false)
+				if err != nil {
+					t.Fatalf("fetch_pages_concurrent: %v", err)
+				}
+
+				if !slices.Equal(items, tc.expected) {
+					t.Fatalf("fetch_pages_concurrent: expected %v, got %v", tc.expected, items)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchPagesConcurrentError(t *testing.T) {
+
+	var (
+		errPage = errors.New("page failed")
+		fetched atomic.Int32
+	)
+
+	fetch := func(ctx context.Context, pageNum int) ([]int, error) {
+		if fetched.Add(1); pageNum == 2 {
+			return nil, errPage
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+		return []int{pageNum}, nil
+	}
+
+	if _, err := fetchPagesConcurrent(context.Background(), fetch, 100, 0, 4, false); !errors.Is(err, errPage) {
+		t.Fatalf("fetch_pages_concurrent: expected %v, got %v", errPage, err)
+	}
+
+	// the error stops the other workers.
+	if n := fetched.Load(); n >= 100 {
+		t.Fatalf("fetch_pages_concurrent: expected the workers to stop, got %d pages fetched", n)
+	}
+}
+
+func TestReadPages(t *testing.T) {
+
+	for _, tc := range []struct {
+		name            string
+		pages, maxItems int
+		expected        []int
+	}{
+		{"empty page", 4, 0, []int{1, 1, 2, 2}},
+		{"max items", 4, 3, []int{1, 1, 2}},
+		{"before empty page", 2, 0, []int{1, 1, 2, 2}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			var serial []int
+
+			for _, pagesConcurrent := range []int{0, 3} {
+
+				cfg := DefaultConfig()
+				cfg.PagesConcurrent = pagesConcurrent
+
+				// the pages end at the first empty page.
+				items, err := readPages(context.Background(), NewClient(WithConfig(cfg)), testPages(3), tc.pages, tc.maxItems)
+				if err != nil {
+					t.Fatalf("read_pages: %v", err)
+				}
+
+				if !slices.Equal(items, tc.expected) {
+					t.Fatalf("read_pages: pages concurrent %d: expected %v, got %v", pagesConcurrent, tc.expected, items)
+				}
+
+				if serial == nil {
+					serial = items
+				} else if !slices.Equal(items, serial) {
+					t.Fatalf("read_pages: expected concurrent %v same as serial %v", items, serial)
+				}
+			}
+		})
+	}
+}
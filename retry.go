@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// retryBackoff is the delay before the first retry, doubled on each next one.
+var retryBackoff = 500 * time.Millisecond
+
+// maxRetryAfter caps the delay requested by the Retry-After header.
+const maxRetryAfter = time.Minute
+
+// retryTransport retries the transient failures (the network errors, 429 and 5xx
+// responses) with the exponential backoff. The retries of all the requests in a run
+// are taken from the shared budget (-retry-budget), so once it is exhausted the
+// failures are returned right away.
+type retryTransport struct {
+	http.RoundTripper
+	retries int
+	// budget is the number of retries left, negative if not limited.
+	budget *atomic.Int64
+}
+
+func newRetryTransport(transport http.RoundTripper, retries, budget int) *retryTransport {
+
+	t := &retryTransport{RoundTripper: transport, retries: retries, budget: new(atomic.Int64)}
+
+	if budget <= 0 {
+		budget = -1
+	}
+
+	t.budget.Store(int64(budget))
+
+	return t
+}
+
+// takeRetry function reports whether the retry is allowed by the budget.
+func (t *retryTransport) takeRetry() bool {
+	if t.budget.Load() < 0 {
+		return true
+	}
+	for left := t.budget.Load(); left > 0; left = t.budget.Load() {
+		if t.budget.CompareAndSwap(left, left-1) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	for attempt := 0; ; attempt++ {
+
+		resp, err := t.RoundTripper.RoundTrip(req)
+		if !isTransient(resp, err) || attempt >= t.retries || req.Context().Err() != nil || !t.takeRetry() {
+			return resp, err
+		}
+
+		stats.retries.Add(1)
+
+		delay := retryBackoff << attempt
+		if resp != nil {
+			if after := retryAfter(resp); after > 0 {
+				delay = after
+			}
+			// release the connection before waiting.
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// isTransient function reports whether the request failure can be retried.
+func isTransient(resp *http.Response, err error) bool {
+
+	if err != nil {
+		var netErr net.Error
+		return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+	}
+
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// retryAfter function returns the delay from the Retry-After header in seconds.
+func retryAfter(resp *http.Response) time.Duration {
+	if sec, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && sec > 0 {
+		return min(time.Duration(sec)*time.Second, maxRetryAfter)
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport(t *testing.T) {
+
+	defer func(d time.Duration) { retryBackoff = d }(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	var hits atomic.Int32
+
+	// every request fails twice before it succeeds.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1)%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name             string
+		retries, budget  int
+		expected         []int
+		expectedRequests int32
+	}{
+		{"no budget", 2, 0, []int{200, 200}, 6},
+		{"budget", 2, 3, []int{200, 503}, 5},
+		{"exhausted budget", 2, 2, []int{200, 503, 503}, 5},
+		{"not enough retries", 1, 0, []int{503}, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			hits.Store(0)
+
+			client := &http.Client{Transport: newRetryTransport(http.DefaultTransport, tc.retries, tc.budget)}
+
+			for i, expected := range tc.expected {
+
+				resp, err := client.Get(srv.URL)
+				if err != nil {
+					t.Fatalf("retry_transport: %v", err)
+				}
+
+				resp.Body.Close()
+
+				if resp.StatusCode != expected {
+					t.Fatalf("retry_transport: request %d: expected %d, got %d", i, expected, resp.StatusCode)
+				}
+			}
+
+			if hits.Load() != tc.expectedRequests {
+				t.Fatalf("retry_transport: expected %d requests, got %d", tc.expectedRequests, hits.Load())
+			}
+		})
+	}
+}
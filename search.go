@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/oiweiwei/lastfmq/htmlq"
+	"golang.org/x/net/html"
+)
+
+// readSearch function returns the canonical artist names found by the query.
+func (c *Client) readSearch(ctx context.Context, query string) ([]string, error) {
+
+	if query == "" {
+		return nil, fmt.Errorf("read_search: query is required")
+	}
+
+	resp, err := c.fetch(ctx, searchPath, url.QueryEscape(query))
+	if err != nil {
+		return nil, fmt.Errorf("read_search: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	tokenizer := html.NewTokenizer(resp.Body)
+
+	var (
+		names     = []string{}
+		startName bool
+	)
+
+	for tok := tokenizer.Next(); tokenizer.Err() == nil; tok = tokenizer.Next() {
+
+		if tok != html.StartTagToken {
+			continue
+		}
+
+		switch htmlq.ContainsAttr(tokenizer,
+			htmlq.TagAttr("p", "class", "grid-items-item-main-text"),
+			htmlq.TagAttr("a", "class", "link-block-target")) {
+		case "grid-items-item-main-text":
+			startName = true
+		case "link-block-target":
+			if !startName {
+				continue
+			}
+			if startName = false; tokenizer.Next() != html.TextToken {
+				continue
+			}
+			if name := htmlq.NormalizeSpace(string(tokenizer.Text())); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	if err := tokenizer.Err(); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("read_search: tokenizer: %w", err)
+	}
+
+	return names, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// serve function serves the band information on the address until the context is done.
+func serve(ctx context.Context, c *Client, addr string) error {
+
+	registerMetrics()
+
+	srv := &http.Server{
+		Addr:        addr,
+		Handler:     c.serveMux(),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve: %w", err)
+	}
+
+	return nil
+}
+
+// serveMux function returns the server routes.
+func (c *Client) serveMux() *http.ServeMux {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /band/{name}", c.handleBand)
+	mux.Handle("GET /metrics", promhttp.Handler())
+
+	return mux
+}
+
+// handleBand function handles the /band/{name}?wiki=1&tags=1&... request. The section
+// query parameters are named after the command-line flags, all=1 enables every section.
+func (c *Client) handleBand(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	query := r.URL.Query()
+
+	all, err := queryBool(query, "all", false)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	with := make(sections)
+
+	for _, s := range allSections() {
+		on, err := queryBool(query, s.name, all)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if on {
+			with[s.name] = true
+		}
+	}
+
+	bandDesc, err := c.readBand(ctx, r.PathValue("name"), with)
+	if err != nil {
+		writeJSON(w, httpStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, bandDesc)
+}
+
+// queryBool function returns the boolean query parameter or the default value if it is not set.
+func queryBool(query url.Values, key string, def bool) (bool, error) {
+	if !query.Has(key) {
+		return def, nil
+	}
+	val, err := strconv.ParseBool(query.Get(key))
+	if err != nil {
+		return false, fmt.Errorf("query: %s: invalid boolean %q", key, query.Get(key))
+	}
+	return val, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
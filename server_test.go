@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleBand(t *testing.T) {
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.RequestURI() {
+		case "/music/Fugazi":
+			http.ServeFile(w, r, filepath.Join("testdata", "overview.html"))
+		case "/music/Fugazi/+tags?page=1":
+			http.ServeFile(w, r, filepath.Join("testdata", "tags.html"))
+		case "/music/Busy":
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	t.Cleanup(upstream.Close)
+
+	c := NewClient(WithBaseURL(upstream.URL), WithHTTPClient(upstream.Client()))
+
+	srv := httptest.NewServer(c.serveMux())
+
+	t.Cleanup(srv.Close)
+
+	for _, tc := range []struct {
+		name, path string
+		code       int
+		tags       bool
+	}{
+		{"overview", "/band/Fugazi", http.StatusOK, false},
+		{"tags", "/band/Fugazi?tags=1", http.StatusOK, true},
+		{"all without tags", "/band/Fugazi?all=1&tags=0&wiki=0&related-tags=0&similar-artists=0&events=0&albums=0&tracks=0", http.StatusOK, false},
+		{"not found", "/band/Nobody", http.StatusNotFound, false},
+		{"rate limited", "/band/Busy", http.StatusTooManyRequests, false},
+		{"bad request", "/band/Fugazi?tags=maybe", http.StatusBadRequest, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			resp, err := http.Get(srv.URL + tc.path)
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.code {
+				t.Fatalf("handle_band: expected status %d, got %d", tc.code, resp.StatusCode)
+			}
+
+			if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+				t.Fatalf("handle_band: expected json, got %q", ct)
+			}
+
+			var v map[string]any
+			if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+				t.Fatalf("json_decode: %v", err)
+			}
+
+			if tc.code != http.StatusOK {
+				if v["error"] == nil {
+					t.Fatalf("handle_band: expected error, got %v", v)
+				}
+				return
+			}
+
+			if v["band_name"] != "Fugazi" {
+				t.Fatalf("handle_band: expected Fugazi, got %v", v)
+			}
+
+			if _, ok := v["tags"]; ok != tc.tags {
+				t.Fatalf("handle_band: expected tags %t, got %v", tc.tags, v["tags"])
+			}
+		})
+	}
+}
+
+// scrapeMetric function returns the metric value with the labels from the /metrics
+// page of the server, zero if the metric is not exposed yet.
+func scrapeMetric(t *testing.T, srvURL, metric string) float64 {
+
+	resp, err := http.Get(srvURL + "/metrics")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("metrics: expected status 200, got %d", resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read_all: %v", err)
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		if val, ok := strings.CutPrefix(line, metric+" "); ok {
+			n, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				t.Fatalf("metrics: %s: %v", metric, err)
+			}
+			return n
+		}
+	}
+
+	return 0
+}
+
+func TestServeMetrics(t *testing.T) {
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join("testdata", "overview.html"))
+	}))
+
+	t.Cleanup(upstream.Close)
+
+	registerMetrics()
+
+	// the cached pages are not counted as the upstream requests.
+	transport := newCacheTransport(&metricsTransport{upstream.Client().Transport}, t.TempDir(), time.Hour, time.Minute, false, false, 0)
+
+	c := NewClient(WithBaseURL(upstream.URL), WithHTTPClient(&http.Client{Transport: transport}))
+
+	srv := httptest.NewServer(c.serveMux())
+
+	t.Cleanup(srv.Close)
+
+	metrics := []string{
+		`lastfmq_upstream_requests_total{code="200"}`,
+		`lastfmq_upstream_request_duration_seconds_count`,
+		`lastfmq_cache_requests_total{result="miss"}`,
+		`lastfmq_cache_requests_total{result="hit"}`,
+	}
+
+	before := make(map[string]float64)
+	for _, metric := range metrics {
+		before[metric] = scrapeMetric(t, srv.URL, metric)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(srv.URL + "/band/Fugazi")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("handle_band: expected status 200, got %d", resp.StatusCode)
+		}
+	}
+
+	// the first request misses the cache and reads last.fm, the second hits the cache.
+	for _, metric := range metrics {
+		if n := scrapeMetric(t, srv.URL, metric) - before[metric]; n != 1 {
+			t.Errorf("metrics: %s: expected 1, got %v", metric, n)
+		}
+	}
+}
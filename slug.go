@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/oiweiwei/lastfmq/htmlq"
+)
+
+// nameSlug function returns the last.fm slug of the band name or slug, and reports
+// whether the argument may be either. last.fm slugifies the names with the query
+// escaping: the spaces are "+" and the rest, including "+", "/" and "%", are escaped,
+// i.e. "Sigur Rós" is Sigur+R%C3%B3s, "AC/DC" is AC%2FDC and "+/-" is %2B%2F-. The
+// argument without spaces, "/", "?" and "#" is taken as the slug, e.g. the
+// "65daysofstatic", "Minor+Threat" or the canonical slug from the url, with the
+// non-ASCII characters escaped, i.e. "Björk" is Bj%C3%B6rk. The slug is
+// ambiguous if it decodes to the name with the leading, trailing or repeated spaces,
+// e.g. "+44", which is the band name rather than the " 44" slug.
+func nameSlug(name string) (string, bool) {
+
+	if strings.ContainsAny(name, " /?#") {
+		return url.QueryEscape(htmlq.NormalizeSpace(name)), false
+	}
+
+	decoded, err := url.QueryUnescape(name)
+	if err != nil {
+		// the stray "%" is the name, i.e. "100%".
+		return url.QueryEscape(name), false
+	}
+
+	return escapeNonASCII(name), decoded == "" || decoded != htmlq.NormalizeSpace(decoded)
+}
+
+// escapeNonASCII function escapes the non-ASCII characters of the slug, same as in the
+// last.fm urls.
+func escapeNonASCII(slug string) string {
+
+	var b strings.Builder
+
+	for _, r := range slug {
+		if r < utf8.RuneSelf {
+			b.WriteRune(r)
+		} else {
+			b.WriteString(url.QueryEscape(string(r)))
+		}
+	}
+
+	return b.String()
+}
+
+// resolveSlug function returns the last.fm slug of the band name or slug. The ambiguous
+// argument is searched for, and is taken as the name if the search has the exact match,
+// or as the slug otherwise.
+func (c *Client) resolveSlug(ctx context.Context, name string) (string, error) {
+
+	slug, ambiguous := nameSlug(name)
+	if !ambiguous {
+		return slug, nil
+	}
+
+	names, err := c.readSearch(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	for _, found := range names {
+		if strings.EqualFold(found, name) {
+			if c.cfg.Verbose {
+				log.Printf("resolve_slug: using the name %q", found)
+			}
+			return url.QueryEscape(found), nil
+		}
+	}
+
+	return slug, nil
+}
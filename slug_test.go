@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestNameSlug(t *testing.T) {
+
+	for _, tc := range []struct {
+		name, slug string
+		ambiguous  bool
+	}{
+		{"Fugazi", "Fugazi", false},
+		{"65daysofstatic", "65daysofstatic", false},
+		{"Minor Threat", "Minor+Threat", false},
+		{"Minor+Threat", "Minor+Threat", false},
+		{"Sigur Rós", "Sigur+R%C3%B3s", false},
+		{"Sigur+R%C3%B3s", "Sigur+R%C3%B3s", false},
+		{"Björk", "Bj%C3%B6rk", false},
+		{"Motörhead", "Mot%C3%B6rhead", false},
+		{"Sigur+Rós", "Sigur+R%C3%B3s", false},
+		{"AC/DC", "AC%2FDC", false},
+		{"+/-", "%2B%2F-", false},
+		{"C+C Music Factory", "C%2BC+Music+Factory", false},
+		{"100%", "100%25", false},
+		{"Sunn+O)))", "Sunn+O)))", false},
+		{"+44", "+44", true},
+		{"Moe+", "Moe+", true},
+	} {
+		slug, ambiguous := nameSlug(tc.name)
+		if slug != tc.slug || ambiguous != tc.ambiguous {
+			t.Errorf("name_slug: %s: expected %q, %t, got %q, %t", tc.name, tc.slug, tc.ambiguous, slug, ambiguous)
+		}
+	}
+}
+
+func TestResolveSlug(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search/artists" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, `<p class="grid-items-item-main-text"><a class="link-block-target" href="/music/%2B44">+44</a></p>`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithBaseURL(srv.URL), WithHTTPClient(srv.Client()))
+
+	for name, expected := range map[string]string{
+		// the search has the exact name.
+		"+44": "%2B44",
+		// no exact name, so is the slug.
+		"Moe+": "Moe+",
+		// not ambiguous, so is not searched for.
+		"Minor+Threat": "Minor+Threat",
+	} {
+		if slug, err := c.resolveSlug(context.Background(), name); err != nil || slug != expected {
+			t.Errorf("resolve_slug: %s: expected %q, got %q, %v", name, expected, slug, err)
+		}
+	}
+}
+
+func TestReadBandSlugCanonicalName(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join("testdata", "overview.html"))
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithBaseURL(srv.URL), WithHTTPClient(srv.Client()))
+
+	// the escaped request url is not the redirect to the canonical name.
+	for _, name := range []string{"Björk", "Motörhead", "Minor+Threat", "C+C Music Factory", "+/-"} {
+
+		desc, err := c.readBand(context.Background(), name, sections{})
+		if err != nil {
+			t.Fatalf("read_band: %s: %v", name, err)
+		}
+
+		if desc.CanonicalName != "" {
+			t.Errorf("read_band: %s: expected no canonical name, got %q", name, desc.CanonicalName)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"sync"
+)
+
+// bandSources is the final urls of the band section pages by the section name.
+type bandSources map[string][]string
+
+// sourceSet collects the band sources (-include-sources).
+type sourceSet struct {
+	mu   sync.Mutex
+	urls bandSources
+}
+
+type sourceKey struct{}
+
+// sectionSource is the context value of the section which the requests belong to.
+type sectionSource struct {
+	set     *sourceSet
+	section string
+}
+
+// withSource function returns the context with the requests recorded as the section sources.
+func withSource(ctx context.Context, set *sourceSet, section string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, &sectionSource{set, section})
+}
+
+// sourced function returns the task with the requests recorded as the section sources,
+// the task is returned as is if the sources are not collected.
+func sourced(set *sourceSet, section string, task func(context.Context) error) func(context.Context) error {
+	if set == nil {
+		return task
+	}
+	return func(ctx context.Context) error {
+		return task(withSource(ctx, set, section))
+	}
+}
+
+// recordSource function records the url as the source of the context section if any.
+func recordSource(ctx context.Context, u *url.URL) {
+
+	src, ok := ctx.Value(sourceKey{}).(*sectionSource)
+	if !ok {
+		return
+	}
+
+	src.set.mu.Lock()
+	defer src.set.mu.Unlock()
+
+	if src.set.urls == nil {
+		src.set.urls = make(bandSources)
+	}
+
+	src.set.urls[src.section] = append(src.set.urls[src.section], u.String())
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema is the schema of the -sqlite database. The band sections refer to the
+// bands table and are replaced on every save.
+const sqliteSchema = `
+PRAGMA foreign_keys = ON;
+
+CREATE TABLE IF NOT EXISTS bands (
+	id                INTEGER PRIMARY KEY,
+	band_name         TEXT NOT NULL UNIQUE,
+	canonical_name    TEXT,
+	scrobbles         INTEGER,
+	listeners         INTEGER,
+	monthly_listeners INTEGER,
+	on_tour           INTEGER,
+	years_active      TEXT,
+	founded_in        TEXT,
+	born              TEXT,
+	born_in           TEXT,
+	image_url         TEXT,
+	summary           TEXT,
+	updated_at        TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+	band_id  INTEGER NOT NULL REFERENCES bands(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	tag      TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS similar_artists (
+	band_id  INTEGER NOT NULL REFERENCES bands(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	name     TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS members (
+	band_id      INTEGER NOT NULL REFERENCES bands(id) ON DELETE CASCADE,
+	name         TEXT NOT NULL,
+	years_active TEXT
+);
+
+CREATE TABLE IF NOT EXISTS refs (
+	band_id   INTEGER NOT NULL REFERENCES bands(id) ON DELETE CASCADE,
+	name      TEXT NOT NULL,
+	reference TEXT
+);
+`
+
+// sqliteStore saves the band descriptions into the sqlite database.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// openSQLite function opens the sqlite database and creates the tables if needed.
+func openSQLite(ctx context.Context, path string) (*sqliteStore, error) {
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open_sqlite: %w", err)
+	}
+
+	// sqlite allows single writer, and the pragmas are per connection.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open_sqlite: create_schema: %w", err)
+	}
+
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// save function upserts the band by the band name and replaces its sections.
+func (s *sqliteStore) save(ctx context.Context, desc *bandDesc) error {
+
+	if desc.BandName == "" {
+		return fmt.Errorf("sqlite_save: band name is required")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite_save: begin: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	var id int64
+
+	if err := tx.QueryRowContext(ctx, `
+INSERT INTO bands (band_name, canonical_name, scrobbles, listeners, monthly_listeners, on_tour,
+	years_active, founded_in, born, born_in, image_url, summary, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (band_name) DO UPDATE SET
+	canonical_name = excluded.canonical_name,
+	scrobbles = excluded.scrobbles,
+	listeners = excluded.listeners,
+	monthly_listeners = excluded.monthly_listeners,
+	on_tour = excluded.on_tour,
+	years_active = excluded.years_active,
+	founded_in = excluded.founded_in,
+	born = excluded.born,
+	born_in = excluded.born_in,
+	image_url = excluded.image_url,
+	summary = excluded.summary,
+	updated_at = excluded.updated_at
+RETURNING id`,
+		desc.BandName, desc.CanonicalName, desc.Scrobbles, desc.Listeners, desc.MonthlyListeners, desc.OnTour,
+		desc.YearsActive, desc.FoundedIn, desc.Born, desc.BornIn, desc.ImageURL, desc.Summary,
+		time.Now().UTC().Format(time.RFC3339)).Scan(&id); err != nil {
+		return fmt.Errorf("sqlite_save: upsert_band: %w", err)
+	}
+
+	for _, table := range []string{"tags", "similar_artists", "members", "refs"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE band_id = ?", id); err != nil {
+			return fmt.Errorf("sqlite_save: delete_%s: %w", table, err)
+		}
+	}
+
+	for i, tag := range desc.Tags {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO tags (band_id, position, tag) VALUES (?, ?, ?)", id, i, tag); err != nil {
+			return fmt.Errorf("sqlite_save: insert_tags: %w", err)
+		}
+	}
+
+	for i, name := range desc.SimilarArtists {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO similar_artists (band_id, position, name) VALUES (?, ?, ?)", id, i, name); err != nil {
+			return fmt.Errorf("sqlite_save: insert_similar_artists: %w", err)
+		}
+	}
+
+	if desc.Wiki != nil {
+
+		for _, member := range desc.Wiki.Members {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO members (band_id, name, years_active) VALUES (?, ?, ?)", id, member.Name, member.YearsActive); err != nil {
+				return fmt.Errorf("sqlite_save: insert_members: %w", err)
+			}
+		}
+
+		for _, ref := range desc.Wiki.Refs {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO refs (band_id, name, reference) VALUES (?, ?, ?)", id, ref.Name, ref.Reference); err != nil {
+				return fmt.Errorf("sqlite_save: insert_refs: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite_save: commit: %w", err)
+	}
+
+	return nil
+}
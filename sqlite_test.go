@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteSaveUpsert(t *testing.T) {
+
+	ctx := context.Background()
+
+	store, err := openSQLite(ctx, filepath.Join(t.TempDir(), "bands.db"))
+	if err != nil {
+		t.Fatalf("open_sqlite: %v", err)
+	}
+
+	defer store.Close()
+
+	desc := &bandDesc{
+		BandName:       "Fugazi",
+		Listeners:      1000,
+		Tags:           []string{"post-hardcore", "punk"},
+		SimilarArtists: []string{"Minor Threat"},
+		Wiki: &Wiki{
+			Members: []*Member{{Name: "Ian MacKaye", YearsActive: "(1987 – present)"}},
+			Refs:    []*Ref{{Name: "Dischord", Reference: "/music/Dischord"}},
+		},
+	}
+
+	if err := store.save(ctx, desc); err != nil {
+		t.Fatalf("sqlite_save: %v", err)
+	}
+
+	desc.Listeners, desc.Tags = 2000, []string{"post-hardcore"}
+
+	if err := store.save(ctx, desc); err != nil {
+		t.Fatalf("sqlite_save: %v", err)
+	}
+
+	for query, expected := range map[string]int{
+		"SELECT COUNT(*) FROM bands":                                   1,
+		"SELECT listeners FROM bands WHERE band_name = 'Fugazi'":       2000,
+		"SELECT COUNT(*) FROM tags":                                    1,
+		"SELECT COUNT(*) FROM similar_artists":                         1,
+		"SELECT COUNT(*) FROM members":                                 1,
+		"SELECT COUNT(*) FROM refs r JOIN bands b ON b.id = r.band_id": 1,
+	} {
+
+		var n int
+
+		if err := store.db.QueryRowContext(ctx, query).Scan(&n); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+
+		if n != expected {
+			t.Errorf("%s: expected %d, got %d", query, expected, n)
+		}
+	}
+}
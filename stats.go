@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// runStats holds the counters for the per-run summary.
+type runStats struct {
+	start    time.Time
+	bands    atomic.Int32
+	failures atomic.Int32
+	filtered atomic.Int32
+	retries  atomic.Int32
+	// budget is the -retry-budget, zero if not limited.
+	budget   int
+	requests atomic.Int64
+	bytes    atomic.Int64
+}
+
+var stats = &runStats{start: time.Now()}
+
+func (s *runStats) String() string {
+
+	retries := strconv.Itoa(int(s.retries.Load()))
+	if s.budget > 0 {
+		retries += "/" + strconv.Itoa(s.budget)
+	}
+
+	return fmt.Sprintf("bands: %d, failures: %d, filtered: %d, requests: %d, retries: %s, bytes: %d, elapsed: %v",
+		s.bands.Load(), s.failures.Load(), s.filtered.Load(), s.requests.Load(), retries, s.bytes.Load(), time.Since(s.start).Round(time.Millisecond))
+}
+
+// statsTransport counts the requests and the bytes read from the response bodies.
+type statsTransport struct {
+	http.RoundTripper
+}
+
+func (t *statsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	stats.requests.Add(1)
+
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = &statsBody{resp.Body}
+
+	return resp, nil
+}
+
+type statsBody struct {
+	io.ReadCloser
+}
+
+func (b *statsBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	stats.bytes.Add(int64(n))
+	return n, err
+}
+
+// printStats function prints the run summary to stderr if -stats is set, and the pages
+// missing in the -offline cache.
+func printStats() {
+
+	if showStats {
+		fmt.Fprintln(os.Stderr, stats)
+	}
+
+	if misses := cacheMisses.list(); len(misses) > 0 {
+		fmt.Fprintf(os.Stderr, "not in cache: %d pages\n", len(misses))
+		for _, u := range misses {
+			fmt.Fprintln(os.Stderr, u)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs is the helper functions available in the -template-file template.
+var templateFuncs = template.FuncMap{
+	// join joins the list with the separator, e.g. {{ .Tags | join ", " }}.
+	"join": func(sep string, list []string) string {
+		return strings.Join(list, sep)
+	},
+	// default returns the default value if the value is empty, e.g. {{ .FoundedIn | default "unknown" }}.
+	"default": func(def, val any) any {
+		if v := reflect.ValueOf(val); !v.IsValid() || v.IsZero() {
+			return def
+		}
+		return val
+	},
+}
+
+// parseTemplate function parses the -template-file template for the template format.
+func parseTemplate(path string) (*template.Template, error) {
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("parse_template: %w", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("parse_template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// writeTemplate function renders the band description with the template, the error
+// contains the template position and the offending field.
+func writeTemplate(b *bytes.Buffer, tmpl *template.Template, desc *bandDesc) error {
+	if err := tmpl.Execute(b, desc); err != nil {
+		return fmt.Errorf("write_template: %s: %w", desc.BandName, err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteTemplate(t *testing.T) {
+
+	desc := &bandDesc{BandName: "Fugazi", Listeners: 751721, Tags: []string{"post-hardcore", "punk"}}
+
+	for _, tc := range []struct {
+		name, template, expected, err string
+	}{
+		{"fields", `{{ .BandName }},{{ .Listeners }},{{ .Tags | join ";" }}`, "Fugazi,751721,post-hardcore;punk", ""},
+		{"default", `{{ .FoundedIn | default "unknown" }} {{ .Listeners | default 0 }}`, "unknown 751721", ""},
+		{"unknown field", `{{ .Label }}`, "", "can't evaluate field Label"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			path := filepath.Join(t.TempDir(), "band.tmpl")
+			if err := os.WriteFile(path, []byte(tc.template), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			tmpl, err := parseTemplate(path)
+			if err != nil {
+				t.Fatalf("parse_template: %v", err)
+			}
+
+			var b bytes.Buffer
+
+			if err := writeTemplate(&b, tmpl, desc); tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("write_template: expected %q error, got %v", tc.err, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("write_template: %v", err)
+			}
+
+			if b.String() != tc.expected {
+				t.Fatalf("write_template: expected %q, got %q", tc.expected, b.String())
+			}
+		})
+	}
+}
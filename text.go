@@ -0,0 +1,323 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiCyan   = "\x1b[36m"
+	ansiYellow = "\x1b[33m"
+)
+
+// choiceFlag function parses the flag value that must be one of the choices.
+func choiceFlag(s *string, choices ...string) func(string) error {
+	return func(v string) error {
+		if !slices.Contains(choices, v) {
+			return fmt.Errorf("unknown value %q, valid values are: %s", v, strings.Join(choices, ","))
+		}
+		*s = v
+		return nil
+	}
+}
+
+// useColor function reports whether the text output should be colorized according
+// to the -color flag. In auto mode the colors are used only if stdout is a terminal.
+func useColor() bool {
+	switch color {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// textWriter renders the band description in the human-friendly text format.
+type textWriter struct {
+	w      io.Writer
+	color  bool
+	fields []string
+	err    error
+}
+
+func (t *textWriter) printf(format string, args ...any) {
+	if t.err == nil {
+		_, t.err = fmt.Fprintf(t.w, format, args...)
+	}
+}
+
+// paint function wraps the text into the ansi color codes if colors are enabled.
+func (t *textWriter) paint(code, s string) string {
+	if !t.color || s == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// show function reports whether the field is selected with the -fields flag.
+func (t *textWriter) show(field string) bool {
+	return len(t.fields) == 0 || slices.Contains(t.fields, field)
+}
+
+func (t *textWriter) header(title string) {
+	t.printf("\n%s\n", t.paint(ansiBold+ansiCyan, title))
+}
+
+func (t *textWriter) value(name, val string) {
+	if val != "" {
+		t.printf("  %s: %s\n", name, val)
+	}
+}
+
+func (t *textWriter) count(name string, n int) {
+	if n != 0 {
+		t.value(name, t.paint(ansiYellow, formatCount(n)))
+	}
+}
+
+// writeText function writes the band description in the text format.
+func writeText(w io.Writer, desc *bandDesc, color bool, fields []string) error {
+
+	t := &textWriter{w: w, color: color, fields: fields}
+
+	name := desc.BandName
+	if desc.CanonicalName != "" && desc.CanonicalName != desc.BandName {
+		name += " (" + desc.CanonicalName + ")"
+	}
+
+	t.printf("%s\n", t.paint(ansiBold, name))
+
+	if t.show("scrobbles") {
+		t.count("Scrobbles", desc.Scrobbles)
+	}
+	if t.show("listeners") {
+		t.count("Listeners", desc.Listeners)
+	}
+	if t.show("listeners_trend") && desc.ListenersTrend != "" {
+		t.value("Listeners Trend", fmt.Sprintf("%s (%+d this week)", desc.ListenersTrend, desc.ListenersDelta))
+	}
+	if t.show("monthly_listeners") {
+		t.count("Monthly Listeners", int(desc.MonthlyListeners))
+	}
+	if t.show("release_count") {
+		t.count("Releases", desc.ReleaseCount)
+	}
+	if t.show("on_tour") && desc.OnTour {
+		t.value("On Tour", "yes")
+	}
+	if t.show("years_active") {
+		t.value("Years Active", desc.YearsActive)
+	}
+	if t.show("founded_in") {
+		t.value("Founded In", desc.FoundedIn)
+	}
+	if t.show("born") {
+		t.value("Born", desc.Born)
+	}
+	if t.show("born_in") {
+		t.value("Born In", desc.BornIn)
+	}
+	if t.show("genre") {
+		t.value("Genre", desc.Genre)
+	}
+	if t.show("image_url") {
+		t.value("Image", desc.ImageURL)
+	}
+
+	if t.show("summary") && desc.Summary != "" {
+		t.printf("\n  %s\n", desc.Summary)
+	}
+
+	if t.show("top_tags") && len(desc.TopTags) > 0 {
+		t.header("Top Tags")
+		t.printf("  %s\n", strings.Join(desc.TopTags, ", "))
+	}
+
+	if t.show("tags") && len(desc.Tags) > 0 {
+		t.header("Tags")
+		t.printf("  %s\n", strings.Join(desc.Tags, ", "))
+	}
+
+	if t.show("related_tags") && len(desc.RelatedTags) > 0 {
+		t.header("Related Tags")
+		t.printf("  %s\n", strings.Join(desc.RelatedTags, ", "))
+	}
+
+	if t.show("similar_artists") && len(desc.SimilarMatch) > 0 {
+
+		t.header("Similar Artists")
+
+		for _, artist := range desc.SimilarMatch {
+			t.printf("  %s", artist.Name)
+			if artist.Match > 0 {
+				t.printf("  %d%%", artist.Match)
+			}
+			if artist.Listeners > 0 {
+				t.printf("  %s listeners", formatCount(artist.Listeners))
+			}
+			t.printf("\n")
+		}
+
+	} else if t.show("similar_artists") && len(desc.SimilarArtists) > 0 {
+		t.header("Similar Artists")
+		t.printf("  %s\n", strings.Join(desc.SimilarArtists, ", "))
+	}
+
+	if t.show("wiki") && desc.Wiki != nil {
+
+		t.header("Wiki")
+
+		for _, member := range desc.Wiki.Members {
+			t.printf("  %s %s\n", member.Name, member.YearsActive)
+		}
+
+		for _, bio := range desc.Wiki.Bio {
+			t.printf("  %s\n", bio)
+		}
+
+		if desc.Wiki.Author != "" {
+			t.value("Author", desc.Wiki.Author)
+		}
+
+		if desc.Wiki.Published != nil {
+			t.value("Published", desc.Wiki.Published.Format(time.DateOnly))
+		}
+
+		links := make([]string, 0, len(desc.Wiki.Links))
+		for name := range desc.Wiki.Links {
+			links = append(links, name)
+		}
+
+		slices.Sort(links)
+
+		for _, name := range links {
+			t.value(name, desc.Wiki.Links[name])
+		}
+	}
+
+	if t.show("events_years") && len(desc.Years) > 0 {
+		t.header("Events Years")
+		t.printf("  %s\n", strings.Join(desc.Years, ", "))
+	}
+
+	if t.show("events") && len(desc.Events) > 0 {
+
+		t.header("Events")
+
+		for _, event := range desc.Events {
+
+			date := event.DateRaw
+			if event.Date != nil {
+				date = event.Date.Format("2006-01-02")
+			}
+
+			var venue []string
+			if event.Address != nil {
+				for _, s := range []string{event.Address.Name, event.Address.Locality, event.Address.Country} {
+					if s != "" {
+						venue = append(venue, s)
+					}
+				}
+			}
+
+			t.printf("  %s  %s", t.paint(ansiYellow, date), strings.Join(venue, ", "))
+			if event.Lineup != "" {
+				t.printf("  (%s)", event.Lineup)
+			}
+			t.printf("\n")
+		}
+	}
+
+	if t.show("top_albums") && len(desc.TopAlbums) > 0 {
+
+		t.header("Top Albums")
+
+		for i, album := range desc.TopAlbums {
+			t.printf("  %2d. %s", i+1, album.Title)
+			if album.Listeners != 0 {
+				t.printf("  %s", t.paint(ansiYellow, formatCount(album.Listeners)))
+			}
+			t.printf("\n")
+		}
+	}
+
+	if t.show("top_tracks") && len(desc.TopTracks) > 0 {
+
+		t.header("Top Tracks")
+
+		for i, track := range desc.TopTracks {
+			t.printf("  %2d. %s", i+1, track.Title)
+			if track.Duration != "" {
+				t.printf("  %s", track.Duration)
+			}
+			if track.Listeners != 0 {
+				t.printf("  %s", t.paint(ansiYellow, formatCount(track.Listeners)))
+			}
+			t.printf("\n")
+		}
+	}
+
+	if t.show("featured_tracks") && len(desc.FeaturedTracks) > 0 {
+
+		t.header("Featured Tracks")
+
+		for i, track := range desc.FeaturedTracks {
+			t.printf("  %2d. %s", i+1, track.Title)
+			if track.Listeners != 0 {
+				t.printf("  %s", t.paint(ansiYellow, formatCount(track.Listeners)))
+			}
+			t.printf("\n")
+		}
+	}
+
+	if t.show("_warnings") && len(desc.Warnings) > 0 {
+
+		t.header("Warnings")
+
+		for _, warning := range desc.Warnings {
+			t.printf("  %s\n", warning)
+		}
+	}
+
+	if t.err != nil {
+		return fmt.Errorf("write_text: %w", t.err)
+	}
+
+	return nil
+}
+
+// formatCount function formats the number with the thousands separators.
+func formatCount(n int) string {
+
+	s := strconv.Itoa(n)
+
+	neg := strings.HasPrefix(s, "-")
+	if neg {
+		s = s[1:]
+	}
+
+	var b strings.Builder
+
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+
+	if neg {
+		return "-" + b.String()
+	}
+
+	return b.String()
+}
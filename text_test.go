@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatCount(t *testing.T) {
+	for n, expected := range map[int]string{
+		0:        "0",
+		999:      "999",
+		1000:     "1,000",
+		751721:   "751,721",
+		18386097: "18,386,097",
+		-12345:   "-12,345",
+	} {
+		if s := formatCount(n); s != expected {
+			t.Errorf("format_count: %d: expected %q, got %q", n, expected, s)
+		}
+	}
+}
+
+func TestWriteText(t *testing.T) {
+
+	desc := &bandDesc{
+		BandName:  "Fugazi",
+		Listeners: 751721,
+		Tags:      []string{"post-hardcore", "punk"},
+		TopTracks: []*Track{{Title: "Waiting Room", Duration: "2:53"}},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		color    bool
+		fields   []string
+		contains []string
+		excludes []string
+	}{
+		{"plain", false, nil, []string{"Fugazi\n", "  Listeners: 751,721\n", "\nTags\n  post-hardcore, punk\n", "   1. Waiting Room  2:53\n"}, []string{"\x1b["}},
+		{"color", true, nil, []string{ansiBold + "Fugazi" + ansiReset, ansiYellow + "751,721" + ansiReset}, nil},
+		{"fields", false, []string{"tags"}, []string{"Tags"}, []string{"Listeners", "Top Tracks"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			var b strings.Builder
+
+			if err := writeText(&b, desc, tc.color, tc.fields); err != nil {
+				t.Fatalf("write_text: %v", err)
+			}
+
+			for _, s := range tc.contains {
+				if !strings.Contains(b.String(), s) {
+					t.Errorf("write_text: expected %q in %q", s, b.String())
+				}
+			}
+
+			for _, s := range tc.excludes {
+				if strings.Contains(b.String(), s) {
+					t.Errorf("write_text: unexpected %q in %q", s, b.String())
+				}
+			}
+		})
+	}
+}
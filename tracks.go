@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/oiweiwei/lastfmq/htmlq"
+	"golang.org/x/net/html"
+)
+
+type Track struct {
+	Title     string `json:"title"`
+	Listeners int    `json:"listeners,omitempty"`
+	Duration  string `json:"duration,omitempty"`
+	// Links are the external store and stream urls by the site name (-links).
+	Links map[string]string `json:"links,omitempty"`
+}
+
+func (c *Client) readTopTracks(ctx context.Context, bandName string) ([]*Track, error) {
+
+	tracks, err := readPages(ctx, c, func(ctx context.Context, pageNum int) ([]*Track, error) {
+		return c.readTopTracksPage(ctx, bandName, pageNum)
+	}, c.cfg.TracksPages, c.cfg.MaxTracks)
+	if err != nil {
+		return nil, fmt.Errorf("read_top_tracks: %w", err)
+	}
+
+	return tracks, nil
+}
+
+func (c *Client) readTopTracksPage(ctx context.Context, bandName string, pageNum int) ([]*Track, error) {
+
+	if bandName == "" {
+		return nil, fmt.Errorf("page %d: band name is required", pageNum)
+	}
+
+	resp, err := c.fetch(ctx, tracksPagePath, bandName, pageNum)
+	if err != nil {
+		return nil, fmt.Errorf("page %d: %w", pageNum, err)
+	}
+
+	defer resp.Body.Close()
+
+	// check page number in case of overflow.
+	if pageNum > 1 && resp.Request.URL.Query().Get("page") != strconv.Itoa(pageNum) {
+		return nil, nil
+	}
+
+	tokenizer := html.NewTokenizer(resp.Body)
+
+	var (
+		tracks     []*Track
+		startChart bool
+		startName  bool
+	)
+
+	for tok := tokenizer.Next(); tokenizer.Err() == nil; tok = tokenizer.Next() {
+
+		switch tok {
+		case html.EndTagToken:
+			if startChart {
+				if htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("table", "")) != "" {
+					startChart = false
+				}
+			}
+		case html.StartTagToken:
+			if !startChart {
+				if htmlq.ContainsAttr(tokenizer, htmlq.TagAttr("table", "class", "chartlist")) != "" {
+					startChart = true
+				}
+				continue
+			}
+
+			switch attr, iter := htmlq.MatchAttr(tokenizer,
+				htmlq.TagAttr("tr", "class", "chartlist-row"),
+				htmlq.TagAttr("td", "class", "chartlist-name", "chartlist-duration"),
+				htmlq.TagAttr("span", "class", "chartlist-count-bar-value"),
+				htmlq.TagAttr("a", "")); attr {
+
+			case "chartlist-row":
+				tracks, startName = append(tracks, &Track{}), false
+			case "":
+				// noop.
+			default:
+
+				if len(tracks) == 0 {
+					continue
+				}
+
+				track := tracks[len(tracks)-1]
+
+				switch attr {
+				case "chartlist-name":
+					startName = true
+				case "a":
+					if !startName {
+						// the play and buy links are outside the name cell.
+						if c.cfg.Links {
+							addLink(&track.Links, iter)
+						}
+						continue
+					}
+					track.Title, startName = htmlq.ReadText(tokenizer, "a"), false
+				case "chartlist-duration":
+					if tokenizer.Next() != html.TextToken {
+						continue
+					}
+					track.Duration = htmlq.NormalizeSpace(string(tokenizer.Text()))
+				case "chartlist-count-bar-value":
+					if tokenizer.Next() != html.TextToken {
+						continue
+					}
+					track.Listeners = parseCount(string(tokenizer.Text()))
+				}
+			}
+		}
+	}
+
+	if err := tokenizer.Err(); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("page %d: tokenizer: %w", pageNum, err)
+	}
+
+	return tracks, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// the build info, set with -ldflags, e.g.:
+//
+//	go build -ldflags "-X main.version=v1.2.3 -X main.commit=$(git rev-parse HEAD) -X main.date=$(date -u +%FT%TZ)"
+//
+// the unset values are taken from the module build info, if any.
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+// versionString function returns the version, commit, build date and go version.
+func versionString() string {
+
+	v, rev, at := version, commit, date
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+
+		if v == "" && info.Main.Version != "(devel)" {
+			v = info.Main.Version
+		}
+
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && rev == "":
+				rev = setting.Value
+			case setting.Key == "vcs.time" && at == "":
+				at = setting.Value
+			}
+		}
+	}
+
+	return fmt.Sprintf("lastfmq %s (commit: %s, date: %s, %s)",
+		orUnknown(v), orUnknown(rev), orUnknown(at), runtime.Version())
+}
+
+// orUnknown function returns "unknown" for the empty build info value.
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}